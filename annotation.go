@@ -0,0 +1,218 @@
+package recaius
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval is a labeled span of audio.
+type Interval struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// Tier is a sequence of non-overlapping intervals in time order.
+type Tier struct {
+	Name      string
+	Intervals []Interval
+}
+
+// Annotation is a set of tiers for linguistic analysis tools,
+// written as a Praat TextGrid or an ELAN annotation document.
+type Annotation struct {
+	Start time.Duration
+	End   time.Duration
+	Tiers []Tier
+}
+
+// NewAnnotation makes utterance, word and reading (Yomi) tiers from word
+// timings of utterances. Utterances without words are skipped.
+func NewAnnotation(us []Utterance) *Annotation {
+	utterances := Tier{Name: "utterance"}
+	words := Tier{Name: "word"}
+	readings := Tier{Name: "reading"}
+	a := &Annotation{}
+	for _, u := range us {
+		if len(u.Words) == 0 {
+			continue
+		}
+		for _, w := range u.Words {
+			iv := Interval{
+				Start: w.BeginTime(),
+				End:   w.EndTime(),
+			}
+			iv.Text = w.Str
+			words.Intervals = append(words.Intervals, iv)
+			if w.Yomi != "" {
+				iv.Text = w.Yomi
+				readings.Intervals = append(readings.Intervals, iv)
+			}
+			if iv.End > a.End {
+				a.End = iv.End
+			}
+		}
+		first, last := u.Words[0], u.Words[len(u.Words)-1]
+		utterances.Intervals = append(utterances.Intervals, Interval{
+			Start: first.BeginTime(),
+			End:   last.EndTime(),
+			Text:  u.Text,
+		})
+	}
+	a.Tiers = []Tier{utterances, words, readings}
+	return a
+}
+
+// WriteTextGrid writes a Praat TextGrid in the long text format. Gaps
+// between intervals are filled with empty intervals as Praat requires.
+func (a *Annotation) WriteTextGrid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "File type = \"ooTextFile\"\nObject class = \"TextGrid\"\n\n")
+	fmt.Fprintf(bw, "xmin = %s\nxmax = %s\ntiers? <exists>\nsize = %d\nitem []:\n",
+		seconds(a.Start), seconds(a.End), len(a.Tiers))
+	for i, t := range a.Tiers {
+		ivs := a.fillGaps(t.Intervals)
+		fmt.Fprintf(bw, "    item [%d]:\n", i+1)
+		fmt.Fprintf(bw, "        class = \"IntervalTier\"\n        name = %s\n", praatString(t.Name))
+		fmt.Fprintf(bw, "        xmin = %s\n        xmax = %s\n", seconds(a.Start), seconds(a.End))
+		fmt.Fprintf(bw, "        intervals: size = %d\n", len(ivs))
+		for j, iv := range ivs {
+			fmt.Fprintf(bw, "        intervals [%d]:\n", j+1)
+			fmt.Fprintf(bw, "            xmin = %s\n            xmax = %s\n", seconds(iv.Start), seconds(iv.End))
+			fmt.Fprintf(bw, "            text = %s\n", praatString(iv.Text))
+		}
+	}
+	return bw.Flush()
+}
+
+func (a *Annotation) fillGaps(ivs []Interval) []Interval {
+	var filled []Interval
+	pos := a.Start
+	for _, iv := range ivs {
+		if iv.Start < pos {
+			iv.Start = pos // overlapping
+		}
+		if iv.End <= iv.Start {
+			continue
+		}
+		if iv.Start > pos {
+			filled = append(filled, Interval{Start: pos, End: iv.Start})
+		}
+		filled = append(filled, iv)
+		pos = iv.End
+	}
+	if pos < a.End || len(filled) == 0 {
+		filled = append(filled, Interval{Start: pos, End: a.End})
+	}
+	return filled
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+func praatString(s string) string {
+	return "\"" + strings.Replace(s, "\"", "\"\"", -1) + "\""
+}
+
+// ELAN annotation document (EAF)
+type eafDocument struct {
+	XMLName         xml.Name            `xml:"ANNOTATION_DOCUMENT"`
+	Author          string              `xml:"AUTHOR,attr"`
+	Format          string              `xml:"FORMAT,attr"`
+	Version         string              `xml:"VERSION,attr"`
+	Header          eafHeader           `xml:"HEADER"`
+	TimeSlots       []eafTimeSlot       `xml:"TIME_ORDER>TIME_SLOT"`
+	Tiers           []eafTier           `xml:"TIER"`
+	LinguisticTypes []eafLinguisticType `xml:"LINGUISTIC_TYPE"`
+}
+
+type eafHeader struct {
+	MediaFile string `xml:"MEDIA_FILE,attr"`
+	TimeUnits string `xml:"TIME_UNITS,attr"`
+}
+
+type eafTimeSlot struct {
+	ID    string `xml:"TIME_SLOT_ID,attr"`
+	Value int64  `xml:"TIME_VALUE,attr"`
+}
+
+type eafTier struct {
+	ID             string          `xml:"TIER_ID,attr"`
+	LinguisticType string          `xml:"LINGUISTIC_TYPE_REF,attr"`
+	Annotations    []eafAnnotation `xml:"ANNOTATION"`
+}
+
+type eafAnnotation struct {
+	Alignable eafAlignableAnnotation `xml:"ALIGNABLE_ANNOTATION"`
+}
+
+type eafAlignableAnnotation struct {
+	ID    string `xml:"ANNOTATION_ID,attr"`
+	Ref1  string `xml:"TIME_SLOT_REF1,attr"`
+	Ref2  string `xml:"TIME_SLOT_REF2,attr"`
+	Value string `xml:"ANNOTATION_VALUE"`
+}
+
+type eafLinguisticType struct {
+	ID            string `xml:"LINGUISTIC_TYPE_ID,attr"`
+	TimeAlignable bool   `xml:"TIME_ALIGNABLE,attr"`
+}
+
+// WriteEAF writes an ELAN annotation document. Times are in milliseconds.
+func (a *Annotation) WriteEAF(w io.Writer, mediaFile string) error {
+	doc := eafDocument{
+		Format:          "3.0",
+		Version:         "3.0",
+		Header:          eafHeader{MediaFile: mediaFile, TimeUnits: "milliseconds"},
+		LinguisticTypes: []eafLinguisticType{{ID: "default-lt", TimeAlignable: true}},
+	}
+	slots := map[time.Duration]string{}
+	var times []time.Duration
+	for _, t := range a.Tiers {
+		for _, iv := range t.Intervals {
+			for _, d := range []time.Duration{iv.Start, iv.End} {
+				if _, ok := slots[d]; !ok {
+					slots[d] = ""
+					times = append(times, d)
+				}
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	for i, d := range times {
+		slots[d] = fmt.Sprintf("ts%d", i+1)
+		doc.TimeSlots = append(doc.TimeSlots, eafTimeSlot{ID: slots[d], Value: int64(d / time.Millisecond)})
+	}
+
+	n := 0
+	for _, t := range a.Tiers {
+		tier := eafTier{ID: t.Name, LinguisticType: "default-lt"}
+		for _, iv := range t.Intervals {
+			n++
+			tier.Annotations = append(tier.Annotations, eafAnnotation{eafAlignableAnnotation{
+				ID:    fmt.Sprintf("a%d", n),
+				Ref1:  slots[iv.Start],
+				Ref2:  slots[iv.End],
+				Value: iv.Text,
+			}})
+		}
+		doc.Tiers = append(doc.Tiers, tier)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "    ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,23 @@
+package recaius
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAnnotation(t *testing.T) {
+	us := subtitleUtterances()
+	us[0].Words[0].Yomi = "ホンジツ"
+	us[0].Words[1].Yomi = "ワ"
+	a := NewAnnotation(us[:1])
+
+	var tg, eaf bytes.Buffer
+	if err := a.WriteTextGrid(&tg); err != nil {
+		t.Fatal("textgrid error:", err)
+	}
+	if err := a.WriteEAF(&eaf, "sample.wav"); err != nil {
+		t.Fatal("eaf error:", err)
+	}
+	checkGolden(t, "annotation.TextGrid", tg.Bytes())
+	checkGolden(t, "annotation.eaf", eaf.Bytes())
+}
@@ -1,18 +1,67 @@
 package recaius
 
+import "time"
+
 type AsrConfig struct {
 	AudioType       string `json:"audio_type,omitempty"`
 	EnergyThreshold int64  `json:"energy_threshold,omitempty"`
 	ResultType      string `json:"result_type,omitempty"`
 	ResultCount     int64  `json:"result_count,omitempty"`
 	ModelID         int64  `json:"model_id"`
-	PhshToTalk      bool   `json:"phsh_to_talk,omitempty"`
+	PushToTalk      bool   `json:"push_to_talk,omitempty"`
+	PhshToTalk      bool   `json:"-"` // Deprecated: use PushToTalk
 	DataLog         int64  `json:"data_log,omitempty"`
 	Comment         string `json:"comment,omitempty"`
 	Retry           bool   `json:"-"`
 	MaxRetry        int64  `json:"-"`
 	MaxConnection   int64  `json:"-"`
 	PollingInterval int64  `json:"-"` // millisecond
+	ChunkSize       int64  `json:"-"` // byte, takes precedence over ChunkDuration
+	ChunkDuration   int64  `json:"-"` // millisecond
+	KeepOpen        bool   `json:"-"` // keep Response of AsrStreamSession open across flushes until Close
+
+	WordTimeUnit time.Duration `json:"-"` // of AsrNBestWord.Begin/End, default millisecond
+
+	Processors []AsrResultProcessor `json:"-"` // applied to every result, e.g. Normalizer
+}
+
+// AudioFormat returns the format of AudioType. Linear PCM is assumed
+// for an unknown type.
+func (c *AsrConfig) AudioFormat() AudioFormat {
+	f, err := AudioFormatOf(c.AudioType)
+	if err != nil {
+		return LinearPCM16k
+	}
+	return f
+}
+
+// size of voice chunks sent by Write/ReadFrom
+func (c *AsrConfig) chunkSize() int {
+	f := c.AudioFormat()
+	if c.ChunkSize > 0 {
+		return f.Align(int(c.ChunkSize))
+	}
+	d := c.ChunkDuration
+	if d <= 0 {
+		d = 1000
+	}
+	return f.Size(time.Duration(d) * time.Millisecond)
+}
+
+// unit of word timings
+func (c *AsrConfig) wordTimeUnit() time.Duration {
+	if c.WordTimeUnit <= 0 {
+		return time.Millisecond
+	}
+	return c.WordTimeUnit
+}
+
+// interval to poll results
+func (c *AsrConfig) pollingInterval() time.Duration {
+	if c.PollingInterval <= 0 {
+		return time.Second
+	}
+	return time.Duration(c.PollingInterval) * time.Millisecond
 }
 
 type asrFlushPayload struct {
@@ -34,8 +83,30 @@ type AsrNBestWord struct {
 	Str        string
 	Confidence float64
 	Yomi       string
-	Begin      int64
-	End        int64
+	Begin      int64         // in AsrConfig.WordTimeUnit, relative to the utterance
+	End        int64         // in AsrConfig.WordTimeUnit, relative to the utterance
+	Offset     time.Duration // start of the utterance from the start of the session
+
+	unit time.Duration // of Begin/End. 0 for millisecond
+}
+
+// The unit of begin/end is not checked against the API here: millisecond
+// is assumed, and AsrConfig.WordTimeUnit overrides it.
+func (w AsrNBestWord) timeUnit() time.Duration {
+	if w.unit == 0 {
+		return time.Millisecond
+	}
+	return w.unit
+}
+
+// BeginTime returns the begin of the word from the start of the session.
+func (w AsrNBestWord) BeginTime() time.Duration {
+	return w.Offset + time.Duration(w.Begin)*w.timeUnit()
+}
+
+// EndTime returns the end of the word from the start of the session.
+func (w AsrNBestWord) EndTime() time.Duration {
+	return w.Offset + time.Duration(w.End)*w.timeUnit()
 }
 
 type AsrNBest struct {
@@ -48,6 +119,7 @@ type AsrNBest struct {
 type AsrResult struct {
 	Type    string
 	Err     error
+	Offset  time.Duration // start of the utterance from the start of the session, see asrConnection.stamp
 	OneBest AsrOneBest
 	NBest   AsrNBest
 	// ConfNet *asrConfNet
@@ -77,6 +149,12 @@ func NewAsrWithConfig(auth *Auth, config *AsrConfig) *Asr {
 	if config.MaxConnection == 0 {
 		config.MaxConnection = 5
 	}
+	if config.PhshToTalk {
+		// map the deprecated field without changing the caller's config
+		c := *config
+		c.PushToTalk = true
+		config = &c
+	}
 	return &Asr{
 		auth:    auth,
 		config:  config,
@@ -87,22 +165,33 @@ func NewAsrWithConfig(auth *Auth, config *AsrConfig) *Asr {
 
 // find free connection, or makae new connection
 func (a *Asr) Session() (*asrSession, error) {
-	conn, err := a.newConnection()
+	conn, err := a.newConnection(a.config)
 	if err != nil {
 		return nil, err
 	}
-	return &asrSession{conn: conn}, nil
+	return newAsrSession(conn), nil
 }
 
 // find free connection, or make new connection
 func (a *Asr) Stream() (*AsrStreamSession, error) {
-	conn, err := a.newConnection()
+	conn, err := a.newConnection(a.config)
 	if err != nil {
 		return nil, err
 	}
 	return newAsrStreamSession(conn), nil
 }
 
+// make new push-to-talk connection regardless of AsrConfig.PushToTalk
+func (a *Asr) PushToTalk() (*AsrPTTSession, error) {
+	config := *a.config
+	config.PushToTalk = true
+	conn, err := a.newConnection(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &AsrPTTSession{sess: newAsrSession(conn)}, nil
+}
+
 // TODO: impl with sound data
 func (a *Asr) Recognize(data []byte) error {
 	return nil
@@ -113,17 +202,17 @@ func (a *Asr) RecognizeFile(path string) error {
 	return nil
 }
 
-func (a *Asr) newConnection() (*asrConnection, error) {
-	// fmt.Println("to wait:", len(a.connSem))
+func (a *Asr) newConnection(config *AsrConfig) (*asrConnection, error) {
+	start := time.Now()
 	a.connSem <- struct{}{}
-	// fmt.Println("go through:", len(a.connSem))
-	conn, err := newAsrConnection(a.auth, a.config, func(conn *asrConnection) {
-		// fmt.Println("to restore:", len(a.connSem))
+	a.auth.metrics().PoolWait(time.Since(start))
+	a.auth.logger().Debug("connection slot taken", "in_use", len(a.connSem), "max", cap(a.connSem))
+	conn, err := newAsrConnection(a.auth, config, func(conn *asrConnection) {
 		<-a.connSem
-		// fmt.Println("restore:", len(a.connSem))
 		return
 	})
 	if err != nil {
+		<-a.connSem // the slot is not taken by a connection
 		return conn, err
 	}
 	return conn, nil
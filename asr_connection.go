@@ -7,6 +7,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 )
@@ -19,24 +20,33 @@ type asrConnection struct {
 	config        *AsrConfig
 	voiceID       int64
 	closeCallback asrConnectionCloseCallback
+	sent          int64         // bytes of audio sent
+	flushed       []int64       // sent bytes at flushes whose results are not finished
+	flushedAt     []time.Time   // times of the flushes
+	offset        time.Duration // start of the current utterance
+	polls         int           // polls since the last RESULT
 }
 
 func newAsrConnection(auth *Auth, config *AsrConfig, closeCallback asrConnectionCloseCallback) (*asrConnection, error) {
-	url := fmt.Sprintf("%s/voices", asrURL)
+	url := fmt.Sprintf("%s/voices", auth.asrURL())
 	payload, err := json.Marshal(config)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := callApi(auth, "POST", url, bytes.NewReader(payload), "application/json")
+	resp, err := callApi(auth, EndpointCreate, "POST", url, bytes.NewReader(payload), "application/json")
 	if err != nil {
+		auth.logger().Warn("connection create failed", "err", err)
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	type rt struct{ UUID string }
 	var t rt
 	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		auth.metrics().Error(ErrorClassDecode)
 		return nil, err
 	}
+	auth.logger().Info("connection created", "uuid", t.UUID, "model_id", config.ModelID, "result_type", config.ResultType)
 	return &asrConnection{
 		ID:            t.UUID,
 		auth:          auth,
@@ -46,17 +56,25 @@ func newAsrConnection(auth *Auth, config *AsrConfig, closeCallback asrConnection
 	}, nil
 }
 
+func (conn *asrConnection) logger() Logger {
+	return conn.auth.logger()
+}
+
+func (conn *asrConnection) metrics() Metrics {
+	return conn.auth.metrics()
+}
+
 func (conn *asrConnection) urlSend() string {
-	return fmt.Sprintf("%s/voices/%s", asrURL, conn.ID)
+	return fmt.Sprintf("%s/voices/%s", conn.auth.asrURL(), conn.ID)
 }
 func (conn *asrConnection) urlFlush() string {
-	return fmt.Sprintf("%s/voices/%s/flush", asrURL, conn.ID)
+	return fmt.Sprintf("%s/voices/%s/flush", conn.auth.asrURL(), conn.ID)
 }
 func (conn *asrConnection) urlResults() string {
-	return fmt.Sprintf("%s/voices/%s/results", asrURL, conn.ID)
+	return fmt.Sprintf("%s/voices/%s/results", conn.auth.asrURL(), conn.ID)
 }
 func (conn *asrConnection) urlDelete() string {
-	return fmt.Sprintf("%s/voices/%s", asrURL, conn.ID)
+	return fmt.Sprintf("%s/voices/%s", conn.auth.asrURL(), conn.ID)
 }
 
 func (conn *asrConnection) Send(buf []byte) ([]AsrResult, error) {
@@ -78,15 +96,18 @@ func (conn *asrConnection) Send(buf []byte) ([]AsrResult, error) {
 	}
 	w.Close()
 
-	// fmt.Println(">call api:", conn.voiceID, conn.urlSend())
-	resp, err := callApi(conn.auth, "PUT", conn.urlSend(), &data, w.FormDataContentType())
-	// fmt.Println("<call done:", conn.voiceID, conn.urlSend())
+	resp, err := callApi(conn.auth, EndpointSend, "PUT", conn.urlSend(), &data, w.FormDataContentType())
 	if err != nil {
+		conn.logger().Warn("send failed", "uuid", conn.ID, "voice_id", conn.voiceID, "size", len(buf), "err", err)
 		return nil, err
 	}
+	conn.logger().Debug("sent", "uuid", conn.ID, "voice_id", conn.voiceID, "size", len(buf))
 	conn.voiceID += 1
+	conn.sent += int64(len(buf))
+	conn.metrics().Sent(len(buf), conn.config.AudioFormat().Duration(int64(len(buf))))
 	defer resp.Body.Close()
-	return conn.checkResponse(resp)
+	// SOS in the response is of this chunk
+	return conn.checkResponse(resp, conn.sent-int64(len(buf)))
 }
 
 func (conn *asrConnection) Flush() ([]AsrResult, error) {
@@ -94,25 +115,51 @@ func (conn *asrConnection) Flush() ([]AsrResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := callApi(conn.auth, "PUT", conn.urlFlush(), bytes.NewReader(data), "application/json")
+	resp, err := callApi(conn.auth, EndpointFlush, "PUT", conn.urlFlush(), bytes.NewReader(data), "application/json")
 	if err != nil {
+		conn.logger().Warn("flush failed", "uuid", conn.ID, "voice_id", conn.voiceID, "err", err)
 		return nil, err
 	}
+	conn.logger().Debug("flushed", "uuid", conn.ID, "voice_id", conn.voiceID)
+	conn.flushed = append(conn.flushed, conn.sent)
+	conn.flushedAt = append(conn.flushedAt, time.Now())
 	defer resp.Body.Close()
-	return conn.checkResponse(resp)
+	return conn.checkResponse(resp, conn.sent)
 }
 
 func (conn *asrConnection) AskResult() ([]AsrResult, error) {
-	resp, err := callApi(conn.auth, "GET", conn.urlResults(), nil, "")
+	conn.polls++
+	resp, err := callApi(conn.auth, EndpointResults, "GET", conn.urlResults(), nil, "")
 	if err != nil {
+		conn.logger().Warn("poll failed", "uuid", conn.ID, "err", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
-	return conn.checkResponse(resp)
+	rs, err := conn.checkResponse(resp, conn.sent)
+	if err == nil {
+		conn.logger().Debug("polled", "uuid", conn.ID, "results", len(rs))
+	}
+	return rs, err
+}
+
+// checkResponse decodes results. sent is the audio sent before the request.
+func (conn *asrConnection) checkResponse(resp *http.Response, sent int64) ([]AsrResult, error) {
+	rs, err := conn.decodeResults(resp)
+	if err != nil {
+		conn.metrics().Error(ErrorClassDecode)
+		return nil, err
+	}
+	conn.stamp(rs, sent)
+	for _, p := range conn.config.Processors {
+		for i := range rs {
+			p.Process(&rs[i])
+		}
+	}
+	return rs, nil
 }
 
 // TODO: support confnet
-func (conn *asrConnection) checkResponse(resp *http.Response) ([]AsrResult, error) {
+func (conn *asrConnection) decodeResults(resp *http.Response) ([]AsrResult, error) {
 	var rs []AsrResult
 	if resp.StatusCode == 200 {
 		resultType := conn.config.ResultType
@@ -143,7 +190,6 @@ func (conn *asrConnection) checkResponse(resp *http.Response) ([]AsrResult, erro
 					rs = append(rs, AsrResult{Type: x.Type, NBest: AsrNBest{Type: x.Type, Status: x.Status, ResultTemp: s}})
 				} else if x.Type == "RESULT" {
 					r := AsrNBest{Type: x.Type, Status: x.Status}
-					// fmt.Println(x.Result)
 					if err := mapstructure.Decode(x.Result, &r.Result); err != nil {
 						return nil, err
 					}
@@ -159,10 +205,52 @@ func (conn *asrConnection) checkResponse(resp *http.Response) ([]AsrResult, erro
 	return rs, nil
 }
 
+// set offsets of the utterance to results. An utterance starts at SOS,
+// which is placed at the audio sent before the request returning it: the
+// start of the chunk with the SOS, or later if it comes by a poll. Without
+// SOS, the utterance starts with the flush cycle: NO_DATA finishes a
+// cycle, and the next one starts from the audio sent at the flush.
+func (conn *asrConnection) stamp(rs []AsrResult, sent int64) {
+	unit := conn.config.wordTimeUnit()
+	for i := range rs {
+		r := &rs[i]
+		if r.Type == "SOS" {
+			conn.offset = conn.config.AudioFormat().Duration(sent)
+		}
+		r.Offset = conn.offset
+		for j := range r.NBest.Result {
+			words := r.NBest.Result[j].Words
+			for k := range words {
+				words[k].Offset = conn.offset
+				words[k].unit = unit
+			}
+		}
+		if r.Type == "RESULT" {
+			conn.metrics().PollsPerUtterance(conn.polls)
+			conn.polls = 0
+		}
+		if r.Type == "NO_DATA" && len(conn.flushed) > 0 {
+			conn.offset = conn.config.AudioFormat().Duration(conn.flushed[0])
+			conn.flushed = conn.flushed[1:]
+			if len(conn.flushedAt) > 0 {
+				conn.metrics().FlushToFinal(time.Since(conn.flushedAt[0]))
+				conn.flushedAt = conn.flushedAt[1:]
+			}
+		}
+	}
+}
+
 func (conn *asrConnection) Close() {
 	if conn.ID == "" {
 		return
 	}
-	callApi(conn.auth, "DELETE", conn.urlDelete(), nil, "")
+	resp, err := callApi(conn.auth, EndpointDelete, "DELETE", conn.urlDelete(), nil, "")
+	if err != nil {
+		conn.logger().Warn("connection delete failed", "uuid", conn.ID, "err", err)
+	} else {
+		resp.Body.Close()
+		conn.logger().Info("connection deleted", "uuid", conn.ID, "sent", conn.sent)
+	}
+	conn.ID = ""
 	conn.closeCallback(conn)
 }
@@ -0,0 +1,63 @@
+package recaius
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectionOffset(t *testing.T) {
+	conn := &asrConnection{config: &AsrConfig{}}
+	word := func(rs []AsrResult) AsrNBestWord {
+		return rs[0].NBest.Result[0].Words[0]
+	}
+	result := func(typ string) []AsrResult {
+		return []AsrResult{{Type: typ, NBest: AsrNBest{Type: typ, Result: []AsrNBestElement{
+			{Words: []AsrNBestWord{{Begin: 200, End: 500}}},
+		}}}}
+	}
+
+	// first cycle: 2 seconds
+	conn.sent = 64000
+	conn.flushed = append(conn.flushed, conn.sent)
+	rs := result("RESULT")
+	conn.stamp(rs, conn.sent)
+	if w := word(rs); w.BeginTime() != 200*time.Millisecond || w.EndTime() != 500*time.Millisecond {
+		t.Fatal("unexpected word timing:", w.BeginTime(), w.EndTime())
+	}
+	conn.stamp([]AsrResult{{Type: "NO_DATA"}}, conn.sent)
+
+	// second cycle
+	conn.sent += 32000
+	conn.flushed = append(conn.flushed, conn.sent)
+	rs = result("RESULT")
+	conn.stamp(rs, conn.sent)
+	if w := word(rs); w.BeginTime() != 2200*time.Millisecond || rs[0].Offset != 2*time.Second {
+		t.Fatal("unexpected offset in second cycle:", w.BeginTime(), rs[0].Offset)
+	}
+}
+
+func TestConnectionUtterances(t *testing.T) {
+	conn := &asrConnection{config: &AsrConfig{}}
+	utterance := func(sent int64, begin int64) AsrNBestWord {
+		conn.stamp([]AsrResult{{Type: "SOS"}}, sent)
+		rs := []AsrResult{{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{
+			{Words: []AsrNBestWord{{Begin: begin, End: begin + 100}}},
+		}}}}
+		conn.stamp(rs, conn.sent)
+		return rs[0].NBest.Result[0].Words[0]
+	}
+
+	// two utterances in a cycle, the second from the chunk at 3s
+	conn.sent = 160000
+	if w := utterance(0, 500); w.BeginTime() != 500*time.Millisecond {
+		t.Fatal("unexpected first utterance:", w.BeginTime())
+	}
+	if w := utterance(96000, 500); w.BeginTime() != 3500*time.Millisecond || w.EndTime() != 3600*time.Millisecond {
+		t.Fatal("unexpected second utterance:", w.BeginTime(), w.EndTime())
+	}
+
+	conn.config.WordTimeUnit = 10 * time.Millisecond
+	if w := utterance(0, 50); w.BeginTime() != 500*time.Millisecond {
+		t.Fatal("WordTimeUnit is not used:", w.BeginTime())
+	}
+}
@@ -0,0 +1,72 @@
+package recaius
+
+import "errors"
+
+var ErrNotPressed = errors.New("push-to-talk is not pressed")
+var ErrPressed = errors.New("push-to-talk is already pressed")
+
+// AsrPTTSession is a push-to-talk session. Audio is accepted only between
+// Press and Release, and one connection is reused for every utterance.
+type AsrPTTSession struct {
+	sess    *asrSession
+	pressed bool
+}
+
+// Press starts an utterance.
+func (p *AsrPTTSession) Press() error {
+	if p.pressed {
+		return ErrPressed
+	}
+	p.pressed = true
+	p.sess.results = nil
+	return nil
+}
+
+func (p *AsrPTTSession) Pressed() bool {
+	return p.pressed
+}
+
+func (p *AsrPTTSession) Send(data []byte) error {
+	if !p.pressed {
+		return ErrNotPressed
+	}
+	return p.sess.Send(data)
+}
+
+func (p *AsrPTTSession) Write(b []byte) (int, error) {
+	if !p.pressed {
+		return 0, ErrNotPressed
+	}
+	return p.sess.Write(b)
+}
+
+// Release finishes the utterance, and returns its final results.
+// Nothing is returned if no audio was sent since Press.
+func (p *AsrPTTSession) Release() ([]AsrResult, error) {
+	if !p.pressed {
+		return nil, ErrNotPressed
+	}
+	p.pressed = false
+	if !p.sess.pending && len(p.sess.w.buf) == 0 {
+		return nil, nil
+	}
+	rs, err := p.sess.FlushWait()
+	p.sess.results = nil
+	if err != nil {
+		return nil, err
+	}
+	var finals []AsrResult
+	for _, r := range rs {
+		if r.Type == "RESULT" {
+			finals = append(finals, r)
+		}
+	}
+	return finals, nil
+}
+
+// Close releases the connection. Audio of a pressed utterance is discarded.
+func (p *AsrPTTSession) Close() error {
+	p.pressed = false
+	p.sess.conn.Close()
+	return nil
+}
@@ -1,41 +1,88 @@
 package recaius
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 type asrSession struct {
 	conn     *asrConnection
+	w        *asrChunkWriter
 	results  []AsrResult
 	buffered bool // 結果が残っている（かもしれない）
+	pending  bool // flushされていない音声がある
+}
+
+func newAsrSession(conn *asrConnection) *asrSession {
+	sess := &asrSession{conn: conn}
+	sess.w = newAsrChunkWriter(conn.config.chunkSize(), sess.send)
+	return sess
 }
 
 func (sess *asrSession) Send(data []byte) error {
+	if err := sess.w.flush(); err != nil {
+		return err
+	}
+	if err := sess.send(data); err != nil {
+		sess.w.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (sess *asrSession) send(data []byte) error {
 	rs, err := sess.conn.Send(data)
 	if err != nil {
 		return err
 	}
 	sess.buffered = true
+	sess.pending = true
 	sess.storeResults(rs)
 	return nil
 }
 
+// Write buffers data and sends it in chunks of AsrConfig.ChunkSize
+// (or ChunkDuration). The remainder is sent on Flush or Close.
+func (sess *asrSession) Write(p []byte) (int, error) {
+	return sess.w.Write(p)
+}
+
+// ReadFrom sends all data from r, so that io.Copy(sess, r) works.
+func (sess *asrSession) ReadFrom(r io.Reader) (int64, error) {
+	return sess.w.ReadFrom(r)
+}
+
 func (sess *asrSession) Flush() error {
+	if err := sess.w.flush(); err != nil {
+		return err
+	}
 	rs, err := sess.conn.Flush()
 	if err != nil {
 		return err
 	}
+	sess.pending = false
 	sess.storeResults(rs)
 	return nil
 }
 
-func (sess *asrSession) Close() {
-	sess.conn.Close()
+// Close flushes written audio and waits for its results before releasing
+// the connection. The results are still available from Wait.
+// After a failed send, the connection is released without flush.
+func (sess *asrSession) Close() error {
+	defer sess.conn.Close()
+	if sess.w.err == nil && (sess.pending || len(sess.w.buf) > 0) {
+		if _, err := sess.FlushWait(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (sess *asrSession) Wait() ([]AsrResult, error) {
 	if !sess.buffered {
 		return sess.results, nil
 	}
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := time.NewTicker(sess.conn.config.pollingInterval())
 	defer ticker.Stop()
 	for {
 		select {
@@ -4,13 +4,18 @@
 
 package recaius
 
-import "time"
+import (
+	"io"
+	"sync"
+	"time"
+)
 
 type asrResultChannel struct {
 	input  chan AsrResult
 	output chan AsrResult
 	length chan int
 	buffer []AsrResult
+	closed bool // input is closed
 }
 
 func newAsrResultChannel() *asrResultChannel {
@@ -20,7 +25,7 @@ func newAsrResultChannel() *asrResultChannel {
 		length: make(chan int),
 		buffer: nil,
 	}
-	go ch.loop()
+	go ch.loop(ch.input)
 	return ch
 }
 
@@ -37,26 +42,20 @@ func (a *asrResultChannel) Len() int {
 }
 
 func (a *asrResultChannel) Close() {
-	if a.input != nil {
+	if !a.closed {
 		close(a.input)
-		a.input = nil
+		a.closed = true
 	}
 }
 
 func (a *asrResultChannel) ClosedIn() bool {
-	return a.input == nil
+	return a.closed
 }
 
-func (a *asrResultChannel) ClosedOut() bool {
-	return a.output == nil
-}
-
-func (a *asrResultChannel) loop() {
-	var i, o chan AsrResult
+func (a *asrResultChannel) loop(i chan AsrResult) {
+	var o chan AsrResult
 	var n AsrResult
 
-	i = a.input
-
 	for i != nil || o != nil {
 		select {
 		case e, open := <-i:
@@ -82,79 +81,183 @@ func (a *asrResultChannel) loop() {
 	}
 	close(a.output)
 	close(a.length)
-	a.output, a.length = nil, nil
 }
 
+// AsrStreamSession emits results to Response while audio is sent.
+// Response is closed when the results of the last flush are finished,
+// so that StartWatch returns after Flush. With AsrConfig.KeepOpen, audio
+// may be flushed many times, and Response is closed after Close.
+// Requests on the connection are serialized, so Write, Flush, StartWatch
+// and Close may be called from different goroutines.
 type AsrStreamSession struct {
-	conn *asrConnection
-	ch   *asrResultChannel
+	conn    *asrConnection
+	ch      *asrResultChannel
+	w       *asrChunkWriter
+	mu      sync.Mutex // held during requests on conn and emits
+	pending bool       // audio sent but not flushed yet
+	flushes int        // flushes whose results are not finished by NO_DATA
+	closing bool       // Close is waiting for the remaining flushes
 }
 
 func newAsrStreamSession(conn *asrConnection) *AsrStreamSession {
-	return &AsrStreamSession{
+	sess := &AsrStreamSession{
 		conn: conn,
 		ch:   newAsrResultChannel(),
 	}
+	sess.w = newAsrChunkWriter(conn.config.chunkSize(), sess.send)
+	return sess
 }
 
 func (sess *AsrStreamSession) Response() <-chan AsrResult {
 	return sess.ch.Out()
 }
 
+// StartWatch polls results until the response is closed. Errors are
+// emitted to Response.
 func (sess *AsrStreamSession) StartWatch() {
-	if sess.ch.ClosedIn() {
-		return
+	if err := sess.watch(); err != nil {
+		sess.emitError(err)
 	}
-	ticker := time.NewTicker(1 * time.Second)
+}
+
+func (sess *AsrStreamSession) watch() error {
+	ticker := time.NewTicker(sess.conn.config.pollingInterval())
 	defer ticker.Stop()
-	for {
-		select {
-		case <-ticker.C:
-			rs, err := sess.conn.AskResult()
-			if err != nil {
-				sess.ch.In() <- AsrResult{Err: err}
-				return
-			}
-			sess.emitResults(rs)
-			if sess.ch.ClosedIn() {
-				return
-			}
+	for range ticker.C {
+		if done, err := sess.poll(); done || err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// poll asks results once, and reports whether the response is closed
+func (sess *AsrStreamSession) poll() (bool, error) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.ch.ClosedIn() {
+		return true, nil
+	}
+	rs, err := sess.conn.AskResult()
+	if err != nil {
+		return false, err
+	}
+	sess.emitResults(rs)
+	return sess.ch.ClosedIn(), nil
 }
 
 func (sess *AsrStreamSession) Send(data []byte) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if err := sess.w.flush(); err != nil {
+		sess.emit(AsrResult{Err: err})
+		return
+	}
+	if err := sess.send(data); err != nil {
+		sess.w.fail(err)
+		sess.emit(AsrResult{Err: err})
+	}
+}
+
+func (sess *AsrStreamSession) send(data []byte) error {
 	rs, err := sess.conn.Send(data)
 	if err != nil {
-		sess.ch.In() <- AsrResult{Err: err}
-		return
+		return err
 	}
+	sess.pending = true
 	sess.emitResults(rs)
-	return
+	return nil
+}
+
+// Write buffers data and sends it in chunks of AsrConfig.ChunkSize
+// (or ChunkDuration). The remainder is sent on Flush or Close.
+// Unlike Send, errors are returned rather than emitted to Response.
+func (sess *AsrStreamSession) Write(p []byte) (int, error) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.w.Write(p)
+}
+
+// ReadFrom sends all data from r, so that io.Copy(sess, r) works.
+func (sess *AsrStreamSession) ReadFrom(r io.Reader) (int64, error) {
+	return copyChunks(sess.Write, r, sess.w.size)
 }
 
 func (sess *AsrStreamSession) Flush() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if err := sess.flush(); err != nil {
+		sess.emit(AsrResult{Err: err})
+	}
+}
+
+func (sess *AsrStreamSession) flush() error {
+	if err := sess.w.flush(); err != nil {
+		return err
+	}
 	rs, err := sess.conn.Flush()
 	if err != nil {
-		sess.ch.In() <- AsrResult{Err: err}
-		return
+		return err
 	}
+	sess.pending = false
+	sess.flushes++
 	sess.emitResults(rs)
-	return
+	return nil
 }
 
-func (sess *AsrStreamSession) Close() {
-	sess.ch.Close()
-	sess.conn.Close()
+// Close flushes written audio and watches until its results are emitted
+// to Response, then releases the connection. It may run along with
+// StartWatch. After a failed send, the connection is released without flush.
+func (sess *AsrStreamSession) Close() error {
+	defer sess.conn.Close()
+	defer func() {
+		sess.mu.Lock()
+		sess.ch.Close()
+		sess.mu.Unlock()
+	}()
+	sess.mu.Lock()
+	if sess.ch.ClosedIn() || sess.w.err != nil {
+		sess.mu.Unlock()
+		return nil
+	}
+	var err error
+	if sess.pending || len(sess.w.buf) > 0 {
+		err = sess.flush()
+	}
+	sess.closing = true
+	done := sess.flushes == 0
+	sess.mu.Unlock()
+	if err != nil || done {
+		return err
+	}
+	return sess.watch()
+}
+
+// emit a result unless the response is closed
+func (sess *AsrStreamSession) emit(r AsrResult) {
+	if !sess.ch.ClosedIn() {
+		sess.ch.In() <- r
+	}
+}
+
+func (sess *AsrStreamSession) emitError(err error) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.emit(AsrResult{Err: err})
 }
 
 func (sess *AsrStreamSession) emitResults(rs []AsrResult) {
-	ch := sess.ch.In()
 	for _, r := range rs {
-		if r.Type == "NO_DATA" {
+		if r.Type != "NO_DATA" {
+			sess.emit(r)
+			continue
+		}
+		if sess.flushes > 0 {
+			sess.flushes--
+		}
+		if sess.flushes == 0 && (sess.closing || !sess.conn.config.KeepOpen) {
 			sess.ch.Close()
 			return
 		}
-		ch <- r
 	}
 }
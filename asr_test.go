@@ -1,11 +1,12 @@
 package recaius
 
 import (
+	"bytes"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestRecognize(t *testing.T) {
@@ -23,21 +24,10 @@ func TestRecognize(t *testing.T) {
 	}
 	defer sess.Close()
 
-	data, err := ioutil.ReadFile("sample.wav") // You need to prepare
-	if err != nil {
-		t.Fatal("file open error: sample.wav:", err)
-	}
-	sampleOffset := 44
-	fmt.Println("Start sending:", sampleOffset, len(data))
-
-	for i := sampleOffset; i < len(data); i += 32000 {
-		j := i + 32000
-		if j > len(data) {
-			j = len(data)
-		}
-		fmt.Printf("Sending: [%d:%d]\n", i, j)
-
-		if err := sess.Send(data[i:j]); err != nil {
+	fmt.Println("Start sending")
+	for data := range readWav("sample.wav", t) {
+		fmt.Printf("Sending: %d bytes\n", len(data))
+		if err := sess.Send(data); err != nil {
 			t.Fatal("Send error:", err)
 		}
 	}
@@ -125,20 +115,10 @@ func TestAsyncRecognize(t *testing.T) {
 		}
 	}()
 
-	data, err := ioutil.ReadFile("sample.wav") // You need to prepare
-	if err != nil {
-		t.Fatal("file open error: sample.wav:", err)
-	}
-	sampleOffset := 44
-	fmt.Println("Start sending:", sampleOffset, len(data))
-	for i := sampleOffset; i < len(data); i += 32000 {
-		j := i + 32000
-		if j > len(data) {
-			j = len(data)
-		}
-		fmt.Printf("Sending: [%d:%d]\n", i, j)
-
-		sess.Send(data[i:j])
+	fmt.Println("Start sending")
+	for data := range readWav("sample.wav", t) {
+		fmt.Printf("Sending: %d bytes\n", len(data))
+		sess.Send(data)
 	}
 	sess.Flush()
 	sess.StartWatch()
@@ -162,21 +142,10 @@ func TestNBest(t *testing.T) {
 	}
 	defer sess.Close()
 
-	data, err := ioutil.ReadFile("sample.wav") // You need to prepare
-	if err != nil {
-		t.Fatal("file open error: sample.wav:", err)
-	}
-	sampleOffset := 44
-	fmt.Println("Start sending:", sampleOffset, len(data))
-
-	for i := sampleOffset; i < len(data); i += 32000 {
-		j := i + 32000
-		if j > len(data) {
-			j = len(data)
-		}
-		fmt.Printf("Sending: [%d:%d]\n", i, j)
-
-		if err := sess.Send(data[i:j]); err != nil {
+	fmt.Println("Start sending")
+	for data := range readWav("sample.wav", t) {
+		fmt.Printf("Sending: %d bytes\n", len(data))
+		if err := sess.Send(data); err != nil {
 			t.Fatal("Send error:", err)
 		}
 	}
@@ -212,20 +181,20 @@ func login(t *testing.T) *Auth {
 }
 
 func readWav(path string, t *testing.T) <-chan []byte {
-	data, err := ioutil.ReadFile(path) // You need to prepare
+	w, err := ReadWavFile(path) // You need to prepare
 	if err != nil {
-		t.Fatal("file open error: sample.wav:", err)
+		t.Fatal("file open error:", path, err)
 	}
-	sampleOffset := 44
+	chunker := NewChunker(bytes.NewReader(w.Data), time.Second, w.Format)
 	ch := make(chan []byte)
 	go func() {
 		defer func() { close(ch) }()
-		for i := sampleOffset; i < len(data); i += 32000 {
-			j := i + 32000
-			if j > len(data) {
-				j = len(data)
+		for {
+			data, err := chunker.Next()
+			if err != nil {
+				return
 			}
-			ch <- data[i:j]
+			ch <- append([]byte(nil), data...) // Next reuses data
 		}
 	}()
 	return ch
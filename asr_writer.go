@@ -0,0 +1,87 @@
+package recaius
+
+import "io"
+
+// asrChunkWriter buffers written audio and passes it to send in chunks of
+// the configured size. The last partial chunk is kept until flush.
+// Once send fails, the buffer is dropped and the writer keeps failing,
+// so that the audio is not sent again out of order.
+type asrChunkWriter struct {
+	size int
+	buf  []byte
+	send func([]byte) error
+	err  error // of the failed send
+}
+
+func newAsrChunkWriter(size int, send func([]byte) error) *asrChunkWriter {
+	return &asrChunkWriter{size: size, send: send}
+}
+
+// Write returns the number of bytes of p accepted: sent or buffered.
+func (w *asrChunkWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	held := len(w.buf) // bytes before p
+	w.buf = append(w.buf, p...)
+	sent := 0
+	for len(w.buf) >= w.size {
+		if err := w.send(w.buf[:w.size]); err != nil {
+			w.fail(err)
+			if sent < held {
+				return 0, err
+			}
+			return sent - held, err
+		}
+		w.buf = w.buf[w.size:]
+		sent += w.size
+	}
+	return len(p), nil
+}
+
+func (w *asrChunkWriter) ReadFrom(r io.Reader) (int64, error) {
+	return copyChunks(w.Write, r, w.size)
+}
+
+// copyChunks reads r in chunks of size and passes them to write
+func copyChunks(write func([]byte) (int, error), r io.Reader, size int) (int64, error) {
+	var n int64
+	buf := make([]byte, size)
+	for {
+		m, err := r.Read(buf)
+		if m > 0 {
+			k, err := write(buf[:m])
+			n += int64(k)
+			if err != nil {
+				return n, err
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+func (w *asrChunkWriter) fail(err error) {
+	w.err = err
+	w.buf = nil
+}
+
+// send buffered remainder
+func (w *asrChunkWriter) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if err := w.send(w.buf); err != nil {
+		w.fail(err)
+		return err
+	}
+	w.buf = nil
+	return nil
+}
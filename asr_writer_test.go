@@ -0,0 +1,60 @@
+package recaius
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestChunkWriter(t *testing.T) {
+	var chunks [][]byte
+	w := newAsrChunkWriter(4, func(b []byte) error {
+		chunks = append(chunks, append([]byte(nil), b...))
+		return nil
+	})
+	n, err := io.Copy(w, bytes.NewReader([]byte("0123456789")))
+	if err != nil {
+		t.Fatal("copy error:", err)
+	}
+	if n != 10 {
+		t.Fatal("unexpected copied size:", n)
+	}
+	if len(chunks) != 2 || string(chunks[0]) != "0123" || string(chunks[1]) != "4567" {
+		t.Fatal("unexpected chunks:", chunks)
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal("flush error:", err)
+	}
+	if len(chunks) != 3 || string(chunks[2]) != "89" {
+		t.Fatal("unexpected remainder:", chunks)
+	}
+}
+
+func TestChunkWriterFailure(t *testing.T) {
+	var sent []string
+	fail := errors.New("send failed")
+	w := newAsrChunkWriter(4, func(b []byte) error {
+		if len(sent) == 1 {
+			return fail
+		}
+		sent = append(sent, string(b))
+		return nil
+	})
+	if n, err := w.Write([]byte("01")); n != 2 || err != nil {
+		t.Fatal("unexpected write:", n, err)
+	}
+	// "0123" is sent, "4567" fails: only "23" of p is accepted
+	if n, err := w.Write([]byte("23456789")); n != 2 || err != fail {
+		t.Fatal("unexpected write:", n, err)
+	}
+	if n, err := w.Write([]byte("ab")); n != 0 || err != fail {
+		t.Fatal("write after failure:", n, err)
+	}
+	if err := w.flush(); err != fail {
+		t.Fatal("flush after failure:", err)
+	}
+	if len(sent) != 1 || len(w.buf) != 0 {
+		t.Fatal("failed audio is kept:", sent, w.buf)
+	}
+}
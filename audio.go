@@ -0,0 +1,124 @@
+package recaius
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"time"
+)
+
+// AudioFormat describes the layout of audio data.
+type AudioFormat struct {
+	SampleRate    int // Hz
+	BitsPerSample int
+	Channels      int
+	BlockAlign    int // byte. the smallest unit which must not be split
+}
+
+// formats accepted by RECAIUS ASR
+var (
+	LinearPCM16k = AudioFormat{SampleRate: 16000, BitsPerSample: 16, Channels: 1, BlockAlign: 2}
+	ADPCM16k     = AudioFormat{SampleRate: 16000, BitsPerSample: 4, Channels: 1, BlockAlign: 1}
+)
+
+// AudioFormatOf returns the format of AsrConfig.AudioType,
+// e.g. "audio/x-linear" or "audio/x-adpcm; rate=8000".
+func AudioFormatOf(audioType string) (AudioFormat, error) {
+	if audioType == "" {
+		return LinearPCM16k, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(audioType)
+	if err != nil {
+		return AudioFormat{}, err
+	}
+	var f AudioFormat
+	switch mediaType {
+	case "audio/x-linear":
+		f = LinearPCM16k
+	case "audio/x-adpcm":
+		f = ADPCM16k
+	default:
+		return AudioFormat{}, fmt.Errorf("audio_type: %s is not supported", audioType)
+	}
+	if rate, ok := params["rate"]; ok {
+		f.SampleRate, err = strconv.Atoi(rate)
+		if err != nil {
+			return AudioFormat{}, fmt.Errorf("invalid rate in audio_type: %s", audioType)
+		}
+	}
+	return f, nil
+}
+
+func (f AudioFormat) BytesPerSecond() int {
+	return f.SampleRate * f.BitsPerSample * f.Channels / 8
+}
+
+// Duration returns the length of n bytes of audio.
+func (f AudioFormat) Duration(n int64) time.Duration {
+	bps := int64(f.BytesPerSecond())
+	if bps <= 0 {
+		return 0
+	}
+	return time.Duration(n * int64(time.Second) / bps)
+}
+
+// Size returns the byte size of audio of length d, rounded down to the
+// block alignment. It is at least one block.
+func (f AudioFormat) Size(d time.Duration) int {
+	return f.Align(int(int64(f.BytesPerSecond()) * int64(d) / int64(time.Second)))
+}
+
+// Align rounds n down to the block alignment. It is at least one block.
+func (f AudioFormat) Align(n int) int {
+	block := f.BlockAlign
+	if block <= 0 {
+		block = 1
+	}
+	n -= n % block
+	if n < block {
+		return block
+	}
+	return n
+}
+
+// Chunker splits audio into chunks of a fixed duration, never splitting
+// a sample or a block.
+type Chunker struct {
+	r    io.Reader
+	buf  []byte
+	size int
+	f    AudioFormat
+}
+
+func NewChunker(r io.Reader, d time.Duration, f AudioFormat) *Chunker {
+	size := f.Size(d)
+	return &Chunker{r: r, buf: make([]byte, size), size: size, f: f}
+}
+
+// Size returns the byte size of chunks.
+func (c *Chunker) Size() int {
+	return c.size
+}
+
+// Next returns the next chunk, or io.EOF at the end of audio.
+// The last chunk may be shorter. A trailing incomplete block is discarded.
+// The chunk is overwritten by the next call; copy it to keep it.
+func (c *Chunker) Next() ([]byte, error) {
+	n, err := io.ReadFull(c.r, c.buf)
+	if err == io.ErrUnexpectedEOF {
+		block := c.f.BlockAlign
+		if block <= 0 {
+			block = 1
+		}
+		n -= n % block
+		if n == 0 {
+			return nil, io.EOF
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.buf[:n], nil
+}
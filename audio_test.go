@@ -0,0 +1,77 @@
+package recaius
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestAudioFormatOf(t *testing.T) {
+	f, err := AudioFormatOf("audio/x-adpcm; rate=8000")
+	if err != nil {
+		t.Fatal("parse error:", err)
+	}
+	if f.SampleRate != 8000 || f.BitsPerSample != 4 {
+		t.Fatal("unexpected format:", f)
+	}
+	if _, err := AudioFormatOf("audio/mpeg"); err == nil {
+		t.Fatal("expect error for unsupported type")
+	}
+	if s := LinearPCM16k.Size(time.Second); s != 32000 {
+		t.Fatal("unexpected size of linear pcm:", s)
+	}
+	if s := ADPCM16k.Size(time.Second); s != 8000 {
+		t.Fatal("unexpected size of adpcm:", s)
+	}
+}
+
+func TestChunker(t *testing.T) {
+	f := AudioFormat{SampleRate: 16000, BitsPerSample: 16, Channels: 2, BlockAlign: 4}
+	// 0.25s of stereo and a trailing incomplete sample
+	data := make([]byte, 16000+3)
+	c := NewChunker(bytes.NewReader(data), 100*time.Millisecond+time.Microsecond, f)
+	if c.Size() != 6400 {
+		t.Fatal("unexpected chunk size:", c.Size())
+	}
+	var sizes []int
+	for {
+		b, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("next error:", err)
+		}
+		sizes = append(sizes, len(b))
+	}
+	if len(sizes) != 3 || sizes[0] != 6400 || sizes[1] != 6400 || sizes[2] != 3200 {
+		t.Fatal("unexpected chunks:", sizes)
+	}
+}
+
+func TestParseWav(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+8))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&b, binary.LittleEndian, []uint32{16000, 32000})
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(8))
+	b.Write([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	w, err := ParseWav(b.Bytes())
+	if err != nil {
+		t.Fatal("parse error:", err)
+	}
+	if w.Format != LinearPCM16k {
+		t.Fatal("unexpected format:", w.Format)
+	}
+	if len(w.Data) != 8 {
+		t.Fatal("unexpected data size:", len(w.Data))
+	}
+}
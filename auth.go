@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -21,6 +22,10 @@ type Auth struct {
 	SpeechRecogZh *ServiceInfo `json:"speech_recog_zhCH,omitempty"`
 	ExpirySec     int64        `json:"expiry_sec,omitempty"`
 	AutoLogin     bool         `json:"-"`
+	BaseURL       string       `json:"-"` // e.g. a mock server. default https://api.recaius.jp
+	HTTPClient    *http.Client `json:"-"` // e.g. with a recording transport. default http.DefaultClient
+	Logger        Logger       `json:"-"` // events of Auth and Asr using it. default silent
+	Metrics       Metrics      `json:"-"` // measurements of Auth and Asr using it. default none
 	expireAt      time.Time    `json:"-"`
 	token         string       `json:"-"`
 }
@@ -41,16 +46,28 @@ func (a *Auth) Login() error {
 	if err != nil {
 		return err
 	}
-	resp, err := http.Post(tokenURL, "application/json", bytes.NewReader(body))
+	req, err := http.NewRequest("POST", a.tokenURL(), bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.do(EndpointLogin, req)
+	if err != nil {
+		a.logger().Warn("login failed", "err", err)
+		return err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 300 {
-		return a.setToken(resp.Body)
+		if err := a.setToken(resp.Body); err != nil {
+			return err
+		}
+		a.logger().Info("logged in", "services", a.services(), "expire_at", a.expireAt)
+		return nil
 	} else if resp.StatusCode >= 400 {
-		return a.errorResponse(resp.Body)
+		err := a.errorResponse(resp.Body)
+		a.logger().Warn("login failed", "status", resp.StatusCode, "err", err)
+		return err
 	}
 	return fmt.Errorf("server error: code=%d", resp.StatusCode)
 }
@@ -64,35 +81,77 @@ func (a *Auth) Extend() error {
 	if !a.Logined() {
 		return a.Login()
 	}
-	client := http.Client{}
-	req, err := makeTokenRequest("PUT", tokenURL, a.token, nil)
+	resp, err := a.extend()
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		// re-login attempt
+		a.logger().Warn("extend failed, login again", "status", resp.StatusCode)
+		return a.Login()
+	}
+	return a.extended(resp)
+}
+
+// ExtendToken extends the token. Unlike Extend, it does not log in again,
+// and returns ResponseError if the server rejects the token.
+func (a *Auth) ExtendToken() error {
+	resp, err := a.extend()
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode < 300 {
-		return a.setToken(resp.Body)
-	} else if resp.StatusCode >= 400 {
-		// re-login attempt
-		return a.Login()
+	if resp.StatusCode >= 400 {
+		return a.errorResponse(resp.Body)
 	}
-	return fmt.Errorf("server error: code=%d", resp.StatusCode)
+	return a.extended(resp)
+}
+
+// You must Close response if not nil
+func (a *Auth) extend() (*http.Response, error) {
+	req, err := makeTokenRequest("PUT", a.tokenURL(), a.token, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return a.do(EndpointExtend, req)
+}
+
+func (a *Auth) extended(resp *http.Response) error {
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("server error: code=%d", resp.StatusCode)
+	}
+	if err := a.setToken(resp.Body); err != nil {
+		return err
+	}
+	a.logger().Info("token extended", "expire_at", a.expireAt)
+	return nil
+}
+
+// ExpireAt returns when the token expires. It is zero before login.
+func (a *Auth) ExpireAt() time.Time {
+	return a.expireAt
+}
+
+// SetToken restores a token taken by another Auth, e.g. one saved by a
+// previous process.
+func (a *Auth) SetToken(token string, expireAt time.Time) {
+	a.token = token
+	a.expireAt = expireAt
 }
 
 func (a *Auth) Token() (string, error) {
 	if a.token == "" {
 		if a.AutoLogin {
+			a.logger().Debug("auto login")
 			err := a.Extend()
 			return a.token, err
 		}
 		return a.token, fmt.Errorf("need login")
 	} else if time.Now().Add(5 * time.Minute).After(a.expireAt) {
 		if a.AutoLogin {
+			a.logger().Debug("refreshing token", "expire_at", a.expireAt)
 			err := a.Extend()
 			return a.token, err
 		}
@@ -103,12 +162,11 @@ func (a *Auth) Token() (string, error) {
 
 func (a *Auth) Logout() error {
 	if a.Logined() {
-		client := http.Client{}
-		req, err := makeTokenRequest("DELETE", tokenURL, a.token, nil)
+		req, err := makeTokenRequest("DELETE", a.tokenURL(), a.token, nil)
 		if err != nil {
 			return err
 		}
-		resp, err := client.Do(req)
+		resp, err := a.do(EndpointLogout, req)
 		if err != nil {
 			return err
 		}
@@ -117,6 +175,7 @@ func (a *Auth) Logout() error {
 		if resp.StatusCode >= 400 {
 			return a.errorResponse(resp.Body)
 		}
+		a.logger().Info("logged out")
 	}
 	return nil
 }
@@ -124,6 +183,7 @@ func (a *Auth) Logout() error {
 func (a *Auth) errorResponse(b io.Reader) error {
 	var rt ResponseError
 	if err := json.NewDecoder(b).Decode(&rt); err != nil {
+		a.metrics().Error(ErrorClassDecode)
 		return err
 	}
 	return rt
@@ -132,6 +192,7 @@ func (a *Auth) errorResponse(b io.Reader) error {
 func (a *Auth) setToken(b io.Reader) error {
 	var rt ResponseToken
 	if err := json.NewDecoder(b).Decode(&rt); err != nil {
+		a.metrics().Error(ErrorClassDecode)
 		return err
 	}
 	a.token = rt.Token
@@ -141,6 +202,73 @@ func (a *Auth) setToken(b io.Reader) error {
 
 // util
 
+func (a *Auth) baseURL() string {
+	if a.BaseURL == "" {
+		return baseURL
+	}
+	return strings.TrimSuffix(a.BaseURL, "/")
+}
+
+func (a *Auth) httpClient() *http.Client {
+	if a.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return a.HTTPClient
+}
+
+// do sends req, and measures it as endpoint
+func (a *Auth) do(endpoint string, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := a.httpClient().Do(req)
+	a.metrics().Request(endpoint, time.Since(start))
+	if err != nil {
+		a.metrics().Error(ErrorClassNetwork)
+	} else if resp.StatusCode >= 400 {
+		a.metrics().Error(statusClass(resp.StatusCode))
+	}
+	return resp, err
+}
+
+func (a *Auth) metrics() Metrics {
+	if a == nil || a.Metrics == nil {
+		return nopMetrics{}
+	}
+	return a.Metrics
+}
+
+func (a *Auth) logger() Logger {
+	if a == nil || a.Logger == nil {
+		return nopLogger{}
+	}
+	return redactLogger{a.Logger}
+}
+
+// names of services to log, e.g. [speech_recog_jaJP]
+func (a *Auth) services() []string {
+	var names []string
+	for _, s := range []struct {
+		name string
+		info *ServiceInfo
+	}{
+		{"speech_recog_jaJP", a.SpeechRecogJa},
+		{"speech_recog_enUS", a.SpeechRecogEn},
+		{"speech_recog_zhCH", a.SpeechRecogZh},
+	} {
+		if s.info != nil {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+func (a *Auth) tokenURL() string {
+	return a.baseURL() + tokenPath
+}
+
+func (a *Auth) asrURL() string {
+	return a.baseURL() + asrPath
+}
+
 func makeTokenRequest(method string, url string, token string, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err == nil {
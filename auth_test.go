@@ -1,8 +1,11 @@
 package recaius
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLogin(t *testing.T) {
@@ -29,3 +32,33 @@ func TestLogin(t *testing.T) {
 	}()
 
 }
+
+func TestBaseURL(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"token":"t0","expiry_sec":600}`))
+	}))
+	defer ts.Close()
+
+	auth := Auth{SpeechRecogJa: &ServiceInfo{"id", "pass"}, BaseURL: ts.URL + "/"}
+	if err := auth.Login(); err != nil {
+		t.Fatal("login failed:", err)
+	}
+	if token, _ := auth.Token(); token != "t0" {
+		t.Fatal("unexpected token:", token)
+	}
+	if d := time.Until(auth.ExpireAt()); d < 590*time.Second || d > 600*time.Second {
+		t.Fatal("unexpected expiry:", d)
+	}
+	if err := auth.Logout(); err != nil {
+		t.Fatal("logout failed:", err)
+	}
+	want := []string{"POST /auth/v2/tokens", "DELETE /auth/v2/tokens"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatal("unexpected requests:", paths)
+	}
+	if u := auth.asrURL(); u != ts.URL+"/asr/v2" {
+		t.Fatal("unexpected asr url:", u)
+	}
+}
@@ -0,0 +1,176 @@
+package recaius
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchSegment is a part of long audio recognized in one session.
+type BatchSegment struct {
+	Index   int
+	Offset  time.Duration // start in the original audio
+	Data    []byte
+	Results []AsrResult
+	Err     error
+}
+
+// BatchTranscriber recognizes long audio by splitting it at low energy
+// points, and recognizing the segments in parallel within
+// AsrConfig.MaxConnection.
+type BatchTranscriber struct {
+	Asr             *Asr
+	SegmentDuration time.Duration // preferred length of a segment. default 30s
+	MaxSegment      time.Duration // a segment is cut here even if no silence is found. default 60s
+	MaxRetry        int           // retries of a failed segment. default AsrConfig.MaxRetry if Retry is set
+}
+
+func NewBatchTranscriber(asr *Asr) *BatchTranscriber {
+	return &BatchTranscriber{Asr: asr}
+}
+
+func (b *BatchTranscriber) segmentDuration() time.Duration {
+	if b.SegmentDuration <= 0 {
+		return 30 * time.Second
+	}
+	return b.SegmentDuration
+}
+
+func (b *BatchTranscriber) maxSegment() time.Duration {
+	if b.MaxSegment < b.segmentDuration() {
+		return 2 * b.segmentDuration()
+	}
+	return b.MaxSegment
+}
+
+func (b *BatchTranscriber) maxRetry() int {
+	if b.MaxRetry == 0 && b.Asr.config.Retry {
+		return int(b.Asr.config.MaxRetry)
+	}
+	return b.MaxRetry
+}
+
+// Split splits audio into segments. For 16bit linear PCM, the quietest frame
+// between SegmentDuration and MaxSegment is chosen as the cut point.
+func (b *BatchTranscriber) Split(data []byte, f AudioFormat) []BatchSegment {
+	var segs []BatchSegment
+	preferred := f.Size(b.segmentDuration())
+	max := f.Size(b.maxSegment())
+	frame := f.Size(20 * time.Millisecond)
+	for start := 0; start < len(data); {
+		end := start + max
+		if end >= len(data) {
+			end = len(data)
+		} else if f.BitsPerSample == 16 {
+			end = quietestPoint(data, start+preferred, end, frame)
+		}
+		segs = append(segs, BatchSegment{
+			Index:  len(segs),
+			Offset: f.Duration(int64(start)),
+			Data:   data[start:end],
+		})
+		start = end
+	}
+	return segs
+}
+
+// start of the quietest frame in data[from:to]
+func quietestPoint(data []byte, from int, to int, frame int) int {
+	best, min := to, -1.0
+	for p := from; p+frame <= to; p += frame {
+		rms, _ := frameFeatures(data[p : p+frame])
+		if min < 0 || rms < min {
+			best, min = p, rms
+		}
+	}
+	return best
+}
+
+// TranscribeWav is Transcribe for a parsed wav file.
+func (b *BatchTranscriber) TranscribeWav(w *Wav) ([]AsrResult, error) {
+	return b.Transcribe(w.Data, w.Format)
+}
+
+// Transcribe recognizes whole audio, and returns results in order.
+// Word timings are corrected to the original audio. If some segments failed,
+// results of the others are returned with the first error.
+func (b *BatchTranscriber) Transcribe(data []byte, f AudioFormat) ([]AsrResult, error) {
+	segs := b.Split(data, f)
+	b.recognize(segs, f)
+
+	var rs []AsrResult
+	var err error
+	for _, seg := range segs {
+		if seg.Err != nil {
+			if err == nil {
+				err = fmt.Errorf("segment %d at %s: %v", seg.Index, seg.Offset, seg.Err)
+			}
+			continue
+		}
+		for _, r := range seg.Results {
+			if r.Type == "NO_DATA" {
+				continue
+			}
+			shiftWords(&r, seg.Offset)
+			rs = append(rs, r)
+		}
+	}
+	return rs, err
+}
+
+func (b *BatchTranscriber) recognize(segs []BatchSegment, f AudioFormat) {
+	queue := make(chan *BatchSegment)
+	var wg sync.WaitGroup
+	for i := int64(0); i < b.Asr.config.MaxConnection; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seg := range queue {
+				for retry := 0; ; retry++ {
+					seg.Results, seg.Err = b.recognizeSegment(seg, f)
+					if seg.Err == nil || retry >= b.maxRetry() {
+						break
+					}
+					b.Asr.auth.logger().Warn("retry segment", "index", seg.Index, "offset", seg.Offset, "retry", retry+1, "err", seg.Err)
+				}
+			}
+		}()
+	}
+	for i := range segs {
+		queue <- &segs[i]
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func (b *BatchTranscriber) recognizeSegment(seg *BatchSegment, f AudioFormat) ([]AsrResult, error) {
+	sess, err := b.Asr.Session()
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Close()
+
+	chunker := NewChunker(bytes.NewReader(seg.Data), time.Second, f)
+	for {
+		data, err := chunker.Next()
+		if err != nil {
+			break
+		}
+		if err := sess.Send(data); err != nil {
+			return nil, err
+		}
+	}
+	return sess.FlushWait()
+}
+
+// move result and its word timings by offset
+func shiftWords(r *AsrResult, offset time.Duration) {
+	r.Offset += offset
+	for i := range r.NBest.Result {
+		words := r.NBest.Result[i].Words
+		for j := range words {
+			words[j].Offset += offset
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package recaius
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+func TestBatchSplit(t *testing.T) {
+	var data []byte
+	data = append(data, makeTone(35*time.Second, 3000)...)
+	data = append(data, makeTone(time.Second, 0)...)
+	data = append(data, makeTone(40*time.Second, 3000)...)
+
+	b := NewBatchTranscriber(NewAsr(&Auth{}))
+	segs := b.Split(data, LinearPCM16k)
+	if len(segs) != 2 {
+		t.Fatal("unexpected segment count:", len(segs))
+	}
+	if segs[1].Offset != 35*time.Second {
+		t.Fatal("not split at silence:", segs[1].Offset)
+	}
+	if len(segs[0].Data)+len(segs[1].Data) != len(data) {
+		t.Fatal("segments do not cover data")
+	}
+}
+
+func fakeBatch(t *testing.T, srv *recaiustest.Server, maxRetry int) (*BatchTranscriber, []byte) {
+	var data []byte
+	data = append(data, makeTone(3*time.Second, 3000)...)
+	data = append(data, makeTone(500*time.Millisecond, 0)...)
+	data = append(data, makeTone(3*time.Second, 3000)...)
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1, ResultType: "nbest", MaxConnection: 1})
+	b := NewBatchTranscriber(asr)
+	b.SegmentDuration = 2 * time.Second
+	b.MaxSegment = 4 * time.Second
+	b.MaxRetry = maxRetry
+	return b, data
+}
+
+func TestBatchTranscribe(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("あ い")
+	b, data := fakeBatch(t, srv, 1)
+
+	segs := b.Split(data, LinearPCM16k)
+	if len(segs) != 2 {
+		t.Fatal("unexpected segment count:", len(segs))
+	}
+	// the first create fails, and the segment is retried on the only connection
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointCreate, Status: 500, Count: 1})
+	rs, err := b.Transcribe(data, LinearPCM16k)
+	if err != nil {
+		t.Fatal("transcribe failed:", err)
+	}
+	words := NewTranscript(rs...).Words()
+	if len(words) != 4 {
+		t.Fatal("unexpected words:", words)
+	}
+	// words of the second segment are shifted by its offset
+	if words[2].BeginTime() != segs[1].Offset || words[3].EndTime() != segs[1].Offset+LinearPCM16k.Duration(int64(len(segs[1].Data))) {
+		t.Fatal("unexpected word timings:", words[2].BeginTime(), words[3].EndTime(), segs[1].Offset)
+	}
+}
+
+func TestBatchFailure(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("あ い")
+	b, data := fakeBatch(t, srv, 0)
+
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointCreate, Status: 500, Count: 1})
+	rs, err := b.Transcribe(data, LinearPCM16k)
+	if err == nil || !strings.HasPrefix(err.Error(), "segment 0 at 0s:") {
+		t.Fatal("failed segment is not reported:", err)
+	}
+	if words := NewTranscript(rs...).Words(); len(words) != 2 || words[0].BeginTime() < 3*time.Second {
+		t.Fatal("results of the other segment are not returned:", words)
+	}
+}
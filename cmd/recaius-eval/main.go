@@ -0,0 +1,135 @@
+// Command recaius-eval recognizes WAV files in a directory, and scores them
+// against reference transcripts (foo.wav and foo.txt).
+//
+//	RECAIUS_ASR_ID=... RECAIUS_ASR_PASS=... recaius-eval -model 1 DIR
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hi6tanaka/recaius"
+	"github.com/hi6tanaka/recaius/eval"
+)
+
+func main() {
+	model := flag.Int64("model", 1, "model ID")
+	energy := flag.Int64("energy", 0, "energy threshold")
+	wer := flag.Bool("wer", false, "score by words instead of characters")
+	verbose := flag.Bool("v", false, "print alignments")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: recaius-eval [flags] DIR")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	id := os.Getenv("RECAIUS_ASR_ID")
+	pass := os.Getenv("RECAIUS_ASR_PASS")
+	if id == "" || pass == "" {
+		fmt.Fprintln(os.Stderr, "RECAIUS_ASR_ID and RECAIUS_ASR_PASS are required")
+		os.Exit(2)
+	}
+	if err := run(id, pass, *model, *energy, flag.Arg(0), *wer, *verbose); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run logs in and evaluates dir. It returns instead of exiting, so that
+// the token is logged out on failure too.
+func run(id string, pass string, model int64, energy int64, dir string, wer bool, verbose bool) error {
+	auth := &recaius.Auth{
+		SpeechRecogJa: &recaius.ServiceInfo{ServiceId: id, Password: pass},
+		AutoLogin:     true,
+	}
+	if err := auth.Login(); err != nil {
+		return fmt.Errorf("login failed: %v", err)
+	}
+	defer auth.Logout()
+
+	asr := recaius.NewAsrWithConfig(auth, &recaius.AsrConfig{
+		ModelID:         model,
+		EnergyThreshold: energy,
+	})
+	return evaluate(asr, dir, wer, verbose)
+}
+
+func evaluate(asr *recaius.Asr, dir string, wer bool, verbose bool) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wav"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	var corpus eval.Corpus
+	failed := 0
+	for _, path := range paths {
+		ref, err := ioutil.ReadFile(strings.TrimSuffix(path, ".wav") + ".txt")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "skip:", err)
+			continue
+		}
+		hyp, err := recognize(asr, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed++
+			continue
+		}
+		var r eval.Result
+		if wer {
+			r = eval.WER(string(ref), hyp)
+		} else {
+			r = eval.CER(string(ref), hyp)
+		}
+		corpus.Add(r)
+		fmt.Printf("%s\t%s\n", filepath.Base(path), r)
+		if verbose {
+			printAlignment(r)
+		}
+	}
+	fmt.Printf("TOTAL\t%s\tfiles=%d failed=%d\n", corpus.Total(), len(corpus.Results), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d files failed", failed)
+	}
+	return nil
+}
+
+func recognize(asr *recaius.Asr, path string) (string, error) {
+	w, err := recaius.ReadWavFile(path)
+	if err != nil {
+		return "", err
+	}
+	rs, err := recaius.NewBatchTranscriber(asr).TranscribeWav(w)
+	if err != nil {
+		return "", err
+	}
+	return recaius.NewTranscript(rs...).Text(), nil
+}
+
+func printAlignment(r eval.Result) {
+	var ops, refs, hyps []string
+	for _, e := range r.Edits {
+		ops = append(ops, e.Op.String())
+		refs = append(refs, orStar(e.Ref))
+		hyps = append(hyps, orStar(e.Hyp))
+	}
+	fmt.Println("  REF:", strings.Join(refs, " "))
+	fmt.Println("  HYP:", strings.Join(hyps, " "))
+	fmt.Println("  OP: ", strings.Join(ops, " "))
+}
+
+func orStar(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hi6tanaka/recaius"
+)
+
+var authCommands = map[string]func(cf *clientFlags, fs *flag.FlagSet, args []string) error{
+	"login":  authLogin,
+	"extend": authExtend,
+	"logout": authLogout,
+	"status": authStatus,
+}
+
+// runAuth manages a token saved across runs. The token is saved per language
+// in the user cache directory.
+func runAuth(args []string) error {
+	if len(args) == 0 || authCommands[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: recaius auth login|extend|logout|status [flags]")
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "help") {
+			return &exitError{exitOK, nil}
+		}
+		return &exitError{exitUsage, nil}
+	}
+	fs := flag.NewFlagSet("auth "+args[0], flag.ContinueOnError)
+	var cf clientFlags
+	cf.register(fs)
+	return authCommands[args[0]](&cf, fs, args[1:])
+}
+
+// savedToken is the token file. A token is used only with the endpoint
+// it was taken from.
+type savedToken struct {
+	Lang     string    `json:"lang"`
+	Endpoint string    `json:"endpoint,omitempty"`
+	Token    string    `json:"token"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+func defaultTokenPath(lang string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "recaius", "token-"+lang+".json")
+}
+
+// valid reports whether t can be used now for the language and endpoint.
+func (t *savedToken) valid(lang string, endpoint string) bool {
+	return t != nil && t.Token != "" && t.Lang == lang && t.Endpoint == endpoint && time.Now().Before(t.ExpireAt)
+}
+
+// loadToken returns nil if no token is saved.
+func loadToken(path string) (*savedToken, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var t savedToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &t, nil
+}
+
+// saveToken writes the token readable only by the user.
+func saveToken(path string, t savedToken) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// restore the saved token of the language and endpoint to auth
+func restoreToken(auth *recaius.Auth, path string, lang string, endpoint string) error {
+	t, err := loadToken(path)
+	if err != nil {
+		return err
+	}
+	if t == nil || t.Token == "" || t.Lang != lang || t.Endpoint != endpoint {
+		return &exitError{exitAuth, fmt.Errorf("not logged in to %s: run recaius auth login", lang)}
+	}
+	auth.SetToken(t.Token, t.ExpireAt)
+	return nil
+}
+
+func printExpiry(verb string, lang string, expireAt time.Time) {
+	fmt.Printf("%s %s: token expires at %s (in %s)\n", verb, lang,
+		expireAt.Format(time.RFC3339), time.Until(expireAt).Round(time.Second))
+}
+
+func authLogin(cf *clientFlags, fs *flag.FlagSet, args []string) error {
+	expiry := fs.Duration("expiry", time.Hour, "lifetime of the token, at least 10m")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *expiry < 10*time.Minute {
+		return usageError("expiry must be at least 10m")
+	}
+	c, err := cf.load()
+	if err != nil {
+		return err
+	}
+	auth, err := c.auth(cf.lang)
+	if err != nil {
+		return err
+	}
+	auth.ExpirySec = int64(*expiry / time.Second)
+	if err := auth.Login(); err != nil {
+		return &exitError{exitAuth, fmt.Errorf("login failed: %v", err)}
+	}
+	token, _ := auth.Token()
+	err = saveToken(cf.tokenPath(), savedToken{
+		Lang:     cf.lang,
+		Endpoint: c.Endpoint,
+		Token:    token,
+		ExpireAt: auth.ExpireAt(),
+	})
+	if err != nil {
+		return fmt.Errorf("save token: %v", err)
+	}
+	printExpiry("logged in to", cf.lang, auth.ExpireAt())
+	return nil
+}
+
+func authExtend(cf *clientFlags, fs *flag.FlagSet, args []string) error {
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c, err := cf.load()
+	if err != nil {
+		return err
+	}
+	path := cf.tokenPath()
+	auth := c.newAuth(cf.lang)
+	if err := restoreToken(auth, path, cf.lang, c.Endpoint); err != nil {
+		return err
+	}
+	// Extend logs in again if the token is rejected, which needs credentials
+	if err := auth.Extend(); err != nil {
+		return &exitError{exitAuth, fmt.Errorf("extend failed: %v", err)}
+	}
+	token, _ := auth.Token()
+	err = saveToken(path, savedToken{Lang: cf.lang, Endpoint: c.Endpoint, Token: token, ExpireAt: auth.ExpireAt()})
+	if err != nil {
+		return fmt.Errorf("save token: %v", err)
+	}
+	printExpiry("extended token of", cf.lang, auth.ExpireAt())
+	return nil
+}
+
+func authLogout(cf *clientFlags, fs *flag.FlagSet, args []string) error {
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c, err := cf.load()
+	if err != nil {
+		return err
+	}
+	path := cf.tokenPath()
+	auth := c.newAuth(cf.lang)
+	if err := restoreToken(auth, path, cf.lang, c.Endpoint); err != nil {
+		return err
+	}
+	// an expired token is just forgotten
+	if time.Now().Before(auth.ExpireAt()) {
+		if err := auth.Logout(); err != nil {
+			return &exitError{exitAuth, fmt.Errorf("logout failed: %v", err)}
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	fmt.Printf("logged out of %s\n", cf.lang)
+	return nil
+}
+
+// authStatus exits with exitAuth if the token is not valid, for scripts.
+// The token is checked with the server unless -local. The API has no call
+// only to check a token, so it is extended.
+func authStatus(cf *clientFlags, fs *flag.FlagSet, args []string) error {
+	local := fs.Bool("local", false, "check the expiry of the saved token only, without extending it on the server")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: recaius auth status [flags]")
+		fmt.Fprintln(os.Stderr, "\nChecks the saved token with the server by extending it, unless -local.")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c, err := cf.load()
+	if err != nil {
+		return err
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.recaius.jp"
+	}
+	fmt.Println("endpoint:", endpoint)
+	fmt.Println("services:")
+	for _, lang := range []string{"ja", "en", "zh"} {
+		if c.configured(lang) {
+			fmt.Printf("  %s: configured (service_id %s)\n", lang, (*c.service(lang)).ServiceId)
+		} else {
+			fmt.Printf("  %s: not configured\n", lang)
+		}
+	}
+
+	path := cf.tokenPath()
+	t, err := loadToken(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case t == nil || t.Token == "" || t.Lang != cf.lang:
+		fmt.Printf("token (%s): none\n", cf.lang)
+	case t.Endpoint != c.Endpoint:
+		fmt.Printf("token (%s): for another endpoint %s\n", cf.lang, t.Endpoint)
+	case time.Now().After(t.ExpireAt):
+		fmt.Printf("token (%s): expired at %s\n", cf.lang, t.ExpireAt.Format(time.RFC3339))
+	case *local:
+		fmt.Printf("token (%s): valid until %s (%s left)\n", cf.lang,
+			t.ExpireAt.Format(time.RFC3339), time.Until(t.ExpireAt).Round(time.Second))
+		return nil
+	default:
+		return verifyToken(cf, c, t)
+	}
+	return &exitError{exitAuth, nil}
+}
+
+// verifyToken extends the saved token, which the server accepts only if
+// the token is valid. A rejected token is not replaced by a new login.
+func verifyToken(cf *clientFlags, c *config, t *savedToken) error {
+	auth := &recaius.Auth{BaseURL: c.Endpoint}
+	auth.SetToken(t.Token, t.ExpireAt)
+	if err := auth.ExtendToken(); err != nil {
+		if _, ok := err.(recaius.ResponseError); ok {
+			fmt.Printf("token (%s): rejected by the server\n", cf.lang)
+			return &exitError{exitAuth, nil}
+		}
+		return fmt.Errorf("check token: %v", err)
+	}
+	token, _ := auth.Token()
+	err := saveToken(cf.tokenPath(), savedToken{Lang: cf.lang, Endpoint: c.Endpoint, Token: token, ExpireAt: auth.ExpireAt()})
+	if err != nil {
+		return fmt.Errorf("save token: %v", err)
+	}
+	fmt.Printf("token (%s): valid until %s (%s left)\n", cf.lang,
+		auth.ExpireAt().Format(time.RFC3339), time.Until(auth.ExpireAt()).Round(time.Second))
+	return nil
+}
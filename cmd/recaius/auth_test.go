@@ -0,0 +1,168 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+// authTest runs auth subcommands against a fake server, with credentials in
+// a config file.
+type authTest struct {
+	t     *testing.T
+	srv   *recaiustest.Server
+	dir   string
+	flags []string
+}
+
+func newAuthTest(t *testing.T) *authTest {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(config, []byte(`{"speech_recog_jaJP":{"service_id":"id","password":"pass"}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("RECAIUS_ASR_ID")
+	os.Unsetenv("RECAIUS_ASR_PASS")
+	srv := recaiustest.NewServer()
+	return &authTest{t, srv, dir, []string{
+		"-config", config,
+		"-endpoint", srv.URL,
+		"-token-file", filepath.Join(dir, "token.json"),
+	}}
+}
+
+func (a *authTest) Close() {
+	a.srv.Close()
+	os.RemoveAll(a.dir)
+}
+
+// run returns the exit code of the subcommand
+func (a *authTest) run(args ...string) int {
+	err := runAuth(append(args[:1:1], append(a.flags, args[1:]...)...))
+	if err == nil {
+		return exitOK
+	}
+	e, ok := err.(*exitError)
+	if !ok {
+		a.t.Fatal("unexpected error:", err)
+	}
+	return e.code
+}
+
+func (a *authTest) clientFlags() *clientFlags {
+	cf := &clientFlags{lang: "ja", endpoint: a.srv.URL, tokenFile: filepath.Join(a.dir, "token.json")}
+	// no credentials
+	cf.config = filepath.Join(a.dir, "empty.json")
+	if err := ioutil.WriteFile(cf.config, []byte(`{}`), 0600); err != nil {
+		a.t.Fatal(err)
+	}
+	return cf
+}
+
+func (a *authTest) logins() int {
+	n := 0
+	for _, req := range a.srv.Requests() {
+		if req.Endpoint == recaiustest.EndpointLogin {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAuthCommands(t *testing.T) {
+	a := newAuthTest(t)
+	defer a.Close()
+
+	if code := a.run("login", "-expiry", "5m"); code != exitUsage {
+		t.Fatal("short expiry is accepted:", code)
+	}
+	if code := a.run("status"); code != exitAuth {
+		t.Fatal("status without token:", code)
+	}
+	if code := a.run("login"); code != exitOK {
+		t.Fatal("login failed:", code)
+	}
+	fi, err := os.Stat(filepath.Join(a.dir, "token.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatal("token is readable by others:", fi.Mode())
+	}
+	if code := a.run("status"); code != exitOK {
+		t.Fatal("status of a valid token:", code)
+	}
+	if code := a.run("extend"); code != exitOK {
+		t.Fatal("extend failed:", code)
+	}
+
+	// the server forgets the token before its local expiry
+	a.srv.ExpireTokens()
+	if code := a.run("status", "-local"); code != exitOK {
+		t.Fatal("local status:", code)
+	}
+	logins := a.logins()
+	if code := a.run("status"); code != exitAuth {
+		t.Fatal("status of a rejected token:", code)
+	}
+	if a.logins() != logins {
+		t.Fatal("status logs in again")
+	}
+
+	if code := a.run("login"); code != exitOK {
+		t.Fatal("login failed:", code)
+	}
+	if code := a.run("logout"); code != exitOK {
+		t.Fatal("logout failed:", code)
+	}
+	if _, err := os.Stat(filepath.Join(a.dir, "token.json")); !os.IsNotExist(err) {
+		t.Fatal("token file is not removed:", err)
+	}
+	if code := a.run("logout"); code != exitAuth {
+		t.Fatal("logout without token:", code)
+	}
+}
+
+func TestLoginSavedToken(t *testing.T) {
+	a := newAuthTest(t)
+	defer a.Close()
+
+	// without a token, login needs credentials
+	if _, _, err := a.clientFlags().login(); err == nil {
+		t.Fatal("login without credentials")
+	}
+
+	if code := a.run("login"); code != exitOK {
+		t.Fatal("login failed:", code)
+	}
+	auth, logout, err := a.clientFlags().login()
+	if err != nil {
+		t.Fatal("saved token is not used:", err)
+	}
+	logout()
+	if token, err := auth.Token(); err != nil || token == "" {
+		t.Fatal("no token:", token, err)
+	}
+	if n := a.logins(); n != 1 {
+		t.Fatal("logged in again:", n)
+	}
+	for _, req := range a.srv.Requests() {
+		if req.Endpoint == recaiustest.EndpointLogout {
+			t.Fatal("saved token is logged out")
+		}
+	}
+
+	// a token of another endpoint is not used
+	cf := a.clientFlags()
+	cf.endpoint = a.srv.URL + "/"
+	if _, _, err := cf.login(); err == nil {
+		t.Fatal("token of another endpoint is used")
+	}
+}
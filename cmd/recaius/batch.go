@@ -0,0 +1,264 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hi6tanaka/recaius"
+)
+
+// extensions of sidecar files
+var sidecarExts = map[string]string{
+	"text": ".txt",
+	"json": ".json",
+	"srt":  ".srt",
+	"vtt":  ".vtt",
+}
+
+func runBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	var cf clientFlags
+	cf.registerAsr(fs)
+	formatList := fs.String("format", "json", "comma separated sidecar formats: text, json, srt and vtt")
+	manifestPath := fs.String("manifest", "", "progress manifest (default DIR/.recaius-batch.jsonl)")
+	maxConn := fs.Int64("max-connection", 5, "connections to the server at once")
+	segment := fs.Duration("segment", 30*time.Second, "split long audio at silence into segments of about this length")
+	retry := fs.Int("retry", 2, "retries of a failed segment")
+	energy := fs.Int64("energy", 0, "energy threshold")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: recaius batch [flags] DIR")
+		fmt.Fprintln(os.Stderr, "\nTranscribes WAV files under DIR, and writes sidecars like foo.json next to foo.wav.")
+		fmt.Fprintln(os.Stderr, "A rerun skips finished files and retries failed ones.")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return &exitError{exitUsage, nil}
+	}
+	dir := fs.Arg(0)
+	var formats []string
+	for _, f := range strings.Split(*formatList, ",") {
+		f = strings.TrimSpace(f)
+		if _, ok := sidecarExts[f]; !ok {
+			return usageError("unknown sidecar format %q", f)
+		}
+		formats = append(formats, f)
+	}
+	if *maxConn <= 0 {
+		return usageError("max-connection must be positive")
+	}
+	if *manifestPath == "" {
+		*manifestPath = filepath.Join(dir, ".recaius-batch.jsonl")
+	}
+
+	files, err := findWavs(dir)
+	if err != nil {
+		return err
+	}
+	m, err := openManifest(*manifestPath)
+	if err != nil {
+		return fmt.Errorf("manifest: %v", err)
+	}
+	defer m.Close()
+
+	auth, logout, err := cf.login()
+	if err != nil {
+		return err
+	}
+	defer logout()
+	resultType := "one_best"
+	if len(formats) > 1 || formats[0] != "text" {
+		resultType = "nbest"
+	}
+	config := &recaius.AsrConfig{
+		ResultType:      resultType,
+		ModelID:         cf.model,
+		EnergyThreshold: *energy,
+		MaxConnection:   *maxConn,
+	}
+	asr := recaius.NewAsrWithConfig(auth, config)
+	defer asr.Close()
+	bt := recaius.NewBatchTranscriber(asr)
+	bt.SegmentDuration = *segment
+	bt.MaxRetry = *retry
+
+	b := &batch{dir: dir, formats: formats, format: config.AudioFormat(), bt: bt, manifest: m, total: len(files)}
+	b.run(files, int(*maxConn))
+	b.summary()
+	if len(b.failures) > 0 {
+		return &exitError{exitFailure, nil}
+	}
+	return nil
+}
+
+// findWavs returns WAV files under dir relative to it, in order.
+func findWavs(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && path != dir && strings.HasPrefix(fi.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !fi.Mode().IsRegular() || !strings.EqualFold(filepath.Ext(path), ".wav") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+type batch struct {
+	dir      string
+	formats  []string
+	format   recaius.AudioFormat // of the audio type
+	bt       *recaius.BatchTranscriber
+	manifest *manifest
+	total    int
+
+	mu       sync.Mutex
+	finished int
+	done     int
+	skipped  int
+	audio    time.Duration // transcribed in this run
+	failures []manifestEntry
+}
+
+// run transcribes files by workers. Sessions of all files share the
+// connections of the Asr, so the workers only keep them busy.
+func (b *batch) run(files []string, workers int) {
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range queue {
+				b.file(file)
+			}
+		}()
+	}
+	for _, file := range files {
+		queue <- file
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func (b *batch) file(file string) {
+	path := filepath.Join(b.dir, file)
+	fi, err := os.Stat(path)
+	if err == nil && b.manifest.done(file, fi) {
+		b.report(manifestEntry{File: file}, true)
+		return
+	}
+	e := manifestEntry{File: file, Status: statusDone}
+	if err == nil {
+		e.Size, e.ModTime = fi.Size(), fi.ModTime()
+		var d time.Duration
+		d, err = b.transcribe(path)
+		e.Duration = d.Seconds()
+	}
+	if err != nil {
+		e.Status, e.Error = statusFailed, err.Error()
+	}
+	e.Time = time.Now()
+	if merr := b.manifest.record(e); merr != nil {
+		fmt.Fprintln(os.Stderr, "recaius: manifest:", merr)
+	}
+	b.report(e, false)
+}
+
+// transcribe a file and write sidecars. Returns the length of the audio.
+// A WAV of another format fails, and is retried by a rerun once converted.
+func (b *batch) transcribe(path string) (time.Duration, error) {
+	w, err := recaius.ReadWavFile(path)
+	if err != nil {
+		return 0, err
+	}
+	d := w.Format.Duration(int64(len(w.Data)))
+	if err := checkWav(w, b.format); err != nil {
+		return d, err
+	}
+	rs, err := b.bt.TranscribeWav(w)
+	if err != nil {
+		return d, err
+	}
+	t := recaius.NewTranscript(rs...)
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, format := range b.formats {
+		if err := writeSidecar(base+sidecarExts[format], format, filepath.Base(path), t); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+// writeSidecar writes a transcript to a temporary file, and renames it, not
+// to leave a partial output on interruption.
+func writeSidecar(path string, format string, name string, t *recaius.Transcript) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".recaius-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := writeTranscript(f, format, name, t); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (b *batch) report(e manifestEntry, skipped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finished++
+	status := e.Status
+	switch {
+	case skipped:
+		b.skipped++
+		status = "skipped"
+	case e.Status == statusDone:
+		b.done++
+		b.audio += time.Duration(e.Duration * float64(time.Second))
+	default:
+		b.failures = append(b.failures, e)
+		status += ": " + e.Error
+	}
+	fmt.Fprintf(os.Stderr, "[%d/%d] %s %s\n", b.finished, b.total, e.File, status)
+}
+
+func (b *batch) summary() {
+	fmt.Printf("files: %d, done: %d, skipped: %d, failed: %d\n", b.total, b.done, b.skipped, len(b.failures))
+	fmt.Printf("audio: %.2f hours (%s) transcribed\n", b.audio.Hours(), b.audio.Round(time.Second))
+	if len(b.failures) > 0 {
+		fmt.Println("failures:")
+		for _, e := range b.failures {
+			fmt.Printf("  %s: %s\n", e.File, e.Error)
+		}
+	}
+}
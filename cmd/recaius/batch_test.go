@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hi6tanaka/recaius"
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+func writeWav(t *testing.T, path string, f recaius.AudioFormat, data []byte) {
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if _, err := (&recaius.Wav{Format: f, Tag: 1, Data: data}).WriteTo(out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchFormat(t *testing.T) {
+	a := newAuthTest(t)
+	defer a.Close()
+	a.srv.DefaultTranscript("こんにちは")
+	dir := filepath.Join(a.dir, "audio")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeWav(t, filepath.Join(dir, "good.wav"), recaius.LinearPCM16k, make([]byte, 32000))
+	stereo := recaius.AudioFormat{SampleRate: 44100, BitsPerSample: 16, Channels: 2, BlockAlign: 4}
+	writeWav(t, filepath.Join(dir, "stereo.wav"), stereo, make([]byte, 176400))
+
+	for i := 0; i < 2; i++ {
+		err := runBatch(append(a.flags, "-format", "text", dir))
+		if e, ok := err.(*exitError); !ok || e.code != exitFailure {
+			t.Fatal("mismatched format does not fail:", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "good.txt")); err != nil {
+		t.Fatal("no sidecar:", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stereo.txt")); !os.IsNotExist(err) {
+		t.Fatal("sidecar of a mismatched format:", err)
+	}
+
+	m, err := openManifest(filepath.Join(dir, ".recaius-batch.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	for file, done := range map[string]bool{"good.wav": true, "stereo.wav": false} {
+		fi, _ := os.Stat(filepath.Join(dir, file))
+		if m.done(file, fi) != done {
+			t.Fatal("unexpected manifest of", file)
+		}
+	}
+	// only the good file is sent, once
+	flushes := 0
+	for _, req := range a.srv.Requests() {
+		if req.Endpoint == recaiustest.EndpointFlush {
+			flushes++
+		}
+	}
+	if flushes != 1 {
+		t.Fatal("unexpected flushes:", flushes)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hi6tanaka/recaius"
+)
+
+// config is the config file. Credentials are in the same form as
+// recaius.Auth.
+type config struct {
+	Ja       *recaius.ServiceInfo `json:"speech_recog_jaJP,omitempty"`
+	En       *recaius.ServiceInfo `json:"speech_recog_enUS,omitempty"`
+	Zh       *recaius.ServiceInfo `json:"speech_recog_zhCH,omitempty"`
+	Endpoint string               `json:"endpoint,omitempty"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "recaius", "config.json")
+}
+
+// loadConfig reads the config file. A missing file is an error only if
+// the path is given explicitly.
+func loadConfig(path string, explicit bool) (*config, error) {
+	c := &config{}
+	if path == "" {
+		return c, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+func (c *config) service(lang string) **recaius.ServiceInfo {
+	switch lang {
+	case "ja":
+		return &c.Ja
+	case "en":
+		return &c.En
+	case "zh":
+		return &c.Zh
+	}
+	return nil
+}
+
+// clientFlags are flags to connect to RECAIUS, common to commands.
+type clientFlags struct {
+	config    string
+	lang      string
+	endpoint  string
+	tokenFile string
+	model     int64
+}
+
+func (cf *clientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&cf.config, "config", "", "config file (default "+defaultConfigPath()+")")
+	fs.StringVar(&cf.lang, "lang", "ja", "language of the service: ja, en or zh")
+	fs.StringVar(&cf.endpoint, "endpoint", "", "API endpoint, e.g. a mock server (default https://api.recaius.jp)")
+	fs.StringVar(&cf.tokenFile, "token-file", "", "token saved by recaius auth login (default "+defaultTokenPath("LANG")+")")
+}
+
+func (cf *clientFlags) tokenPath() string {
+	if cf.tokenFile != "" {
+		return cf.tokenFile
+	}
+	return defaultTokenPath(cf.lang)
+}
+
+// registerAsr registers flags for recognition too.
+func (cf *clientFlags) registerAsr(fs *flag.FlagSet) {
+	cf.register(fs)
+	fs.Int64Var(&cf.model, "model", 1, "model ID")
+}
+
+// load reads the config file, and applies the environment and flags to it.
+// RECAIUS_ASR_ID and RECAIUS_ASR_PASS are credentials of the language.
+func (cf *clientFlags) load() (*config, error) {
+	path, explicit := cf.config, cf.config != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	c, err := loadConfig(path, explicit)
+	if err != nil {
+		return nil, usageError("config: %v", err)
+	}
+	service := c.service(cf.lang)
+	if service == nil {
+		return nil, usageError("unknown language %q: want ja, en or zh", cf.lang)
+	}
+	if id, pass := os.Getenv("RECAIUS_ASR_ID"), os.Getenv("RECAIUS_ASR_PASS"); id != "" || pass != "" {
+		*service = &recaius.ServiceInfo{ServiceId: id, Password: pass}
+	}
+	if cf.endpoint != "" {
+		c.Endpoint = cf.endpoint
+	}
+	return c, nil
+}
+
+func (c *config) configured(lang string) bool {
+	s := c.service(lang)
+	return s != nil && *s != nil && (*s).ServiceId != "" && (*s).Password != ""
+}
+
+// newAuth makes an Auth for the language. Credentials may be missing.
+func (c *config) newAuth(lang string) *recaius.Auth {
+	// only the language is needed, and others may be incomplete
+	auth := &recaius.Auth{AutoLogin: true, BaseURL: c.Endpoint}
+	switch lang {
+	case "ja":
+		auth.SpeechRecogJa = c.Ja
+	case "en":
+		auth.SpeechRecogEn = c.En
+	case "zh":
+		auth.SpeechRecogZh = c.Zh
+	}
+	return auth
+}
+
+// auth makes an Auth for the language, which must have credentials.
+func (c *config) auth(lang string) (*recaius.Auth, error) {
+	if !c.configured(lang) {
+		return nil, &exitError{exitAuth, fmt.Errorf("no credentials for %s: set RECAIUS_ASR_ID and RECAIUS_ASR_PASS, or write them in the config file", lang)}
+	}
+	return c.newAuth(lang), nil
+}
+
+// login makes an Auth with the token saved by recaius auth login, or logs
+// in if it is missing or expired. Credentials come from the environment and
+// the config file. The returned func logs out the token taken here, and
+// keeps the saved one.
+func (cf *clientFlags) login() (*recaius.Auth, func(), error) {
+	c, err := cf.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	t, err := loadToken(cf.tokenPath())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "recaius: ignore saved token:", err)
+	} else if t.valid(cf.lang, c.Endpoint) {
+		auth := c.newAuth(cf.lang)
+		auth.SetToken(t.Token, t.ExpireAt)
+		return auth, func() {}, nil
+	}
+
+	auth, err := c.auth(cf.lang)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := auth.Login(); err != nil {
+		return nil, nil, &exitError{exitAuth, fmt.Errorf("login failed: %v", err)}
+	}
+	return auth, func() { auth.Logout() }, nil
+}
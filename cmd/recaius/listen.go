@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hi6tanaka/recaius"
+)
+
+func runListen(args []string) error {
+	fs := flag.NewFlagSet("listen", flag.ContinueOnError)
+	var cf clientFlags
+	cf.registerAsr(fs)
+	jsonMode := fs.Bool("json", false, "print events as JSON lines")
+	resultType := fs.String("result-type", "", "one_best or nbest (default nbest with -json, one_best otherwise)")
+	chunk := fs.Duration("chunk", 250*time.Millisecond, "duration of audio sent at once")
+	audioType := fs.String("audio-type", "audio/x-linear", "audio type of stdin")
+	energy := fs.Int64("energy", 0, "energy threshold")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: recaius listen [flags] < AUDIO")
+		fmt.Fprintln(os.Stderr, "\nAUDIO is raw 16kHz 16bit PCM, e.g. from arecord -f S16_LE -r 16000 -c 1 -t raw.")
+		fmt.Fprintln(os.Stderr, "Interrupt to stop listening and wait for the last results.")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("listen reads stdin, and takes no arguments")
+	}
+	switch *resultType {
+	case "":
+		*resultType = "one_best"
+		if *jsonMode {
+			*resultType = "nbest"
+		}
+	case "one_best", "nbest":
+	default:
+		return usageError("unknown result type %q", *resultType)
+	}
+	if _, err := recaius.AudioFormatOf(*audioType); err != nil {
+		return usageError("%v", err)
+	}
+	if *chunk <= 0 {
+		return usageError("chunk must be positive")
+	}
+
+	auth, logout, err := cf.login()
+	if err != nil {
+		return err
+	}
+	defer logout()
+	asr := recaius.NewAsrWithConfig(auth, &recaius.AsrConfig{
+		AudioType:       *audioType,
+		ResultType:      *resultType,
+		ModelID:         cf.model,
+		EnergyThreshold: *energy,
+		ChunkDuration:   int64(*chunk / time.Millisecond),
+	})
+	defer asr.Close()
+	sess, err := asr.Stream()
+	if err != nil {
+		return err
+	}
+
+	// the first interrupt stops reading, and the second one kills
+	stop := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		signal.Stop(sig)
+		close(stop)
+	}()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(sess, &stoppableReader{os.Stdin, stop})
+		// Close flushes, and closes Response after the last results
+		if cerr := sess.Close(); err == nil {
+			err = cerr
+		}
+		errc <- err
+	}()
+
+	var display resultDisplay = newTextDisplay(os.Stdout)
+	if *jsonMode {
+		display = &jsonDisplay{enc: json.NewEncoder(os.Stdout)}
+	}
+	failed := false
+	for r := range sess.Response() {
+		if r.Err != nil {
+			failed = true
+		}
+		if err := display.Show(r); err != nil {
+			return err
+		}
+	}
+	if err := display.Close(); err != nil {
+		return err
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	if failed {
+		return &exitError{exitFailure, nil}
+	}
+	return nil
+}
+
+// stoppableReader returns io.EOF once stop is closed. A blocking Read of
+// the underlying reader is not interrupted, but live audio comes soon.
+type stoppableReader struct {
+	r    io.Reader
+	stop <-chan struct{}
+}
+
+func (s *stoppableReader) Read(p []byte) (int, error) {
+	select {
+	case <-s.stop:
+		return 0, io.EOF
+	default:
+		return s.r.Read(p)
+	}
+}
+
+type resultDisplay interface {
+	Show(r recaius.AsrResult) error
+	Close() error
+}
+
+// textDisplay rewrites the partial result on the last line of a terminal,
+// and commits final results on their own lines. Partial results are not
+// shown unless the output is a terminal.
+type textDisplay struct {
+	w       io.Writer
+	tty     bool
+	t       *recaius.Transcript
+	partial bool // the last line is a partial result
+}
+
+func newTextDisplay(f *os.File) *textDisplay {
+	tty := false
+	if fi, err := f.Stat(); err == nil {
+		tty = fi.Mode()&os.ModeCharDevice != 0
+	}
+	return &textDisplay{w: f, tty: tty, t: recaius.NewTranscript()}
+}
+
+func (d *textDisplay) Show(r recaius.AsrResult) error {
+	if r.Err != nil {
+		d.clear()
+		fmt.Fprintln(os.Stderr, "recaius:", r.Err)
+		return nil
+	}
+	n := len(d.t.Utterances())
+	d.t.Add(r)
+	switch r.Type {
+	case "TMP_RESULT":
+		if !d.tty {
+			return nil
+		}
+		d.clear()
+		_, err := fmt.Fprint(d.w, d.t.Partial())
+		d.partial = true
+		return err
+	case "RESULT":
+		d.clear()
+		us := d.t.Utterances()
+		if len(us) == n {
+			return nil // nothing recognized
+		}
+		_, err := fmt.Fprintln(d.w, us[n].Text)
+		return err
+	}
+	return nil
+}
+
+// erase the partial result
+func (d *textDisplay) clear() {
+	if d.partial {
+		fmt.Fprint(d.w, "\r\x1b[K")
+		d.partial = false
+	}
+}
+
+func (d *textDisplay) Close() error {
+	d.clear()
+	return nil
+}
+
+// listenEvent is a JSON line of listen. Time is the start of the utterance
+// from the start of audio in seconds.
+type listenEvent struct {
+	Type      string         `json:"type"` // start, partial, final or error
+	Time      float64        `json:"time"`
+	Text      string         `json:"text,omitempty"`
+	Utterance *jsonUtterance `json:"utterance,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+type jsonDisplay struct {
+	enc *json.Encoder
+}
+
+func (d *jsonDisplay) Show(r recaius.AsrResult) error {
+	ev := listenEvent{Time: r.Offset.Seconds()}
+	switch {
+	case r.Err != nil:
+		ev.Type, ev.Error = "error", r.Err.Error()
+	case r.Type == "SOS":
+		ev.Type = "start"
+	case r.Type == "TMP_RESULT":
+		ev.Type, ev.Text = "partial", r.OneBest.Str
+		if r.NBest.Type != "" {
+			ev.Text = r.NBest.ResultTemp
+		}
+	case r.Type == "RESULT":
+		us := recaius.NewTranscript(r).Utterances()
+		if len(us) == 0 {
+			return nil
+		}
+		ju := newJSONUtterance(us[0])
+		ev.Type, ev.Text, ev.Utterance = "final", ju.Text, &ju
+	default:
+		return nil
+	}
+	return d.enc.Encode(ev)
+}
+
+func (d *jsonDisplay) Close() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hi6tanaka/recaius"
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+func TestListen(t *testing.T) {
+	a := newAuthTest(t)
+	defer a.Close()
+	a.srv.TranscribeChunks(4, "いち に\nさん よん")
+	audio := make([]byte, 64000) // 4 chunks of 500ms
+
+	out, code := runCommand(t, runListen, append(a.flags, "-chunk", "500ms"), audio)
+	if code != exitOK || out != "いち に\nさん よん\n" {
+		t.Fatalf("listen: %d %q", code, out)
+	}
+
+	out, code = runCommand(t, runListen, append(a.flags, "-chunk", "500ms", "-json"), audio)
+	if code != exitOK {
+		t.Fatal("listen -json:", code)
+	}
+	var types []string
+	var events []listenEvent
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var ev listenEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, ev.Type)
+		events = append(events, ev)
+	}
+	if got := strings.Join(types, " "); got != "start partial final start partial final" {
+		t.Fatal("unexpected events:", got)
+	}
+	if ev := events[5]; ev.Text != "さん よん" || ev.Time != 1 || ev.Utterance == nil || len(ev.Utterance.Words) != 2 {
+		t.Fatal("unexpected final event:", ev)
+	}
+
+	a.srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointSend, Status: 500})
+	if _, code := runCommand(t, runListen, a.flags, audio); code != exitFailure {
+		t.Fatal("listen with a server error:", code)
+	}
+}
+
+func TestTextDisplay(t *testing.T) {
+	var out bytes.Buffer
+	d := &textDisplay{w: &out, tty: true, t: recaius.NewTranscript()}
+	for _, r := range []recaius.AsrResult{
+		{Type: "SOS"},
+		{Type: "TMP_RESULT", OneBest: recaius.AsrOneBest{Type: "TMP_RESULT", Str: "いち"}},
+		{Type: "TMP_RESULT", OneBest: recaius.AsrOneBest{Type: "TMP_RESULT", Str: "いち に"}},
+		{Type: "RESULT", OneBest: recaius.AsrOneBest{Type: "RESULT", Str: "いち に"}},
+	} {
+		if err := d.Show(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Close()
+	if want := "いち\r\x1b[Kいち に\r\x1b[Kいち に\n"; out.String() != want {
+		t.Fatalf("unexpected display: %q", out.String())
+	}
+}
@@ -0,0 +1,127 @@
+// Command recaius is a command-line client of RECAIUS speech recognition.
+//
+//	recaius transcribe [flags] [FILE...]
+//	recaius listen [flags] < AUDIO
+//	recaius batch [flags] DIR
+//	recaius auth login|extend|logout|status [flags]
+//
+// Credentials are read from RECAIUS_ASR_ID and RECAIUS_ASR_PASS, or from a
+// config file (default $XDG_CONFIG_HOME/recaius/config.json) like
+//
+//	{
+//	  "speech_recog_jaJP": {"service_id": "...", "password": "..."},
+//	  "endpoint": "https://api.recaius.jp"
+//	}
+//
+// Commands use the token saved by recaius auth login until it expires, and
+// log in with the credentials otherwise.
+//
+// Exit codes are 0 on success, 1 if recognition failed, 2 on usage errors,
+// and 3 if credentials are missing or login failed.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	exitAuth    = 3
+)
+
+// exitError makes the command exit with code. A nil err exits silently.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func usageError(format string, args ...interface{}) error {
+	return &exitError{exitUsage, fmt.Errorf(format, args...)}
+}
+
+type command struct {
+	run   func(args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"transcribe": {runTranscribe, "recognize audio files or stdin"},
+	"listen":     {runListen, "recognize live audio from stdin"},
+	"auth":       {runAuth, "manage the token saved across runs"},
+	"batch":      {runBatch, "recognize WAV files in a directory with resumable progress"},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
+		os.Exit(exitOK)
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "recaius: unknown command %q\n", name)
+		usage()
+		os.Exit(exitUsage)
+	}
+	os.Exit(exitCode(cmd.run(os.Args[2:])))
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: recaius COMMAND [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun 'recaius COMMAND -h' for flags of a command")
+}
+
+// print err, and return the exit code for it
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		if e.err != nil {
+			fmt.Fprintln(os.Stderr, "recaius:", e.err)
+		}
+		return e.code
+	}
+	fmt.Fprintln(os.Stderr, "recaius:", err)
+	return exitFailure
+}
+
+// parseFlags parses args, and maps flag errors to exit codes.
+// The flag package has already printed the error.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return &exitError{exitOK, nil}
+		}
+		return &exitError{exitUsage, nil}
+	}
+	return nil
+}
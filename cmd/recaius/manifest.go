@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// manifestEntry records the outcome of a file in a batch run. Entries are
+// appended as files finish, and the last one of a file wins.
+type manifestEntry struct {
+	File     string    `json:"file"` // relative to the directory
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Duration float64   `json:"duration"` // audio seconds
+	Time     time.Time `json:"time"`
+}
+
+const (
+	statusDone   = "done"
+	statusFailed = "failed"
+)
+
+// manifest is an append-only JSON lines file, so that an interrupted run
+// loses at most the files in progress.
+type manifest struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries map[string]manifestEntry
+}
+
+func openManifest(path string) (*manifest, error) {
+	m := &manifest{entries: map[string]manifestEntry{}}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var e manifestEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue // empty, or cut by a crash
+		}
+		m.entries[e.File] = e
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		// terminate the cut line not to corrupt the next entry
+		if _, err := f.Write([]byte("\n")); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	m.f = f
+	return m, nil
+}
+
+// done reports whether the file was finished and has not changed since.
+func (m *manifest) done(file string, fi os.FileInfo) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[file]
+	return ok && e.Status == statusDone && e.Size == fi.Size() && e.ModTime.Equal(fi.ModTime())
+}
+
+func (m *manifest) record(e manifestEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := m.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	m.entries[e.File] = e
+	return nil
+}
+
+func (m *manifest) Close() error {
+	return m.f.Close()
+}
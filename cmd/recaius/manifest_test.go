@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifestResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wav := filepath.Join(dir, "a.wav")
+	if err := ioutil.WriteFile(wav, []byte("RIFF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat(wav)
+	path := filepath.Join(dir, "manifest.jsonl")
+
+	m, err := openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.record(manifestEntry{File: "a.wav", Status: statusFailed, Size: fi.Size(), ModTime: fi.ModTime()})
+	m.record(manifestEntry{File: "a.wav", Status: statusDone, Size: fi.Size(), ModTime: fi.ModTime()})
+	m.record(manifestEntry{File: "b.wav", Status: statusFailed})
+	m.Close()
+
+	// a line cut by a crash is ignored
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"file":"b.wav","sta`)
+	f.Close()
+
+	m, err = openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.record(manifestEntry{File: "c.wav", Status: statusDone})
+	m.Close()
+	m, err = openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, ok := m.entries["c.wav"]; !ok {
+		t.Fatal("entry after a cut line is lost")
+	}
+	if !m.done("a.wav", fi) {
+		t.Fatal("a.wav should be done")
+	}
+	if m.done("b.wav", fi) {
+		t.Fatal("failed b.wav should be retried")
+	}
+	// modified after done
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(wav, later, later)
+	fi, _ = os.Stat(wav)
+	if m.done("a.wav", fi) {
+		t.Fatal("modified a.wav should be transcribed again")
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hi6tanaka/recaius"
+)
+
+var formats = []string{"text", "json", "jsonl", "srt", "vtt"}
+
+func validFormat(format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTranscript is the JSON output for an input. Times are in seconds.
+type jsonTranscript struct {
+	File       string          `json:"file"`
+	Text       string          `json:"text"`
+	Utterances []jsonUtterance `json:"utterances"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type jsonUtterance struct {
+	Text       string     `json:"text"`
+	Confidence float64    `json:"confidence,omitempty"`
+	Start      *float64   `json:"start,omitempty"`
+	End        *float64   `json:"end,omitempty"`
+	Words      []jsonWord `json:"words,omitempty"`
+}
+
+type jsonWord struct {
+	Text       string  `json:"text"`
+	Yomi       string  `json:"yomi,omitempty"`
+	Confidence float64 `json:"confidence"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+}
+
+func newJSONTranscript(name string, t *recaius.Transcript, err error) jsonTranscript {
+	jt := jsonTranscript{File: name, Text: t.Text(), Utterances: []jsonUtterance{}}
+	if err != nil {
+		jt.Error = err.Error()
+	}
+	for _, u := range t.Utterances() {
+		jt.Utterances = append(jt.Utterances, newJSONUtterance(u))
+	}
+	return jt
+}
+
+func newJSONUtterance(u recaius.Utterance) jsonUtterance {
+	ju := jsonUtterance{Text: u.Text, Confidence: u.Confidence}
+	for _, w := range u.Words {
+		ju.Words = append(ju.Words, jsonWord{
+			Text:       w.Str,
+			Yomi:       w.Yomi,
+			Confidence: w.Confidence,
+			Start:      w.BeginTime().Seconds(),
+			End:        w.EndTime().Seconds(),
+		})
+	}
+	if n := len(ju.Words); n > 0 {
+		ju.Start, ju.End = &ju.Words[0].Start, &ju.Words[n-1].End
+	}
+	return ju
+}
+
+// resultWriter writes transcripts of inputs in a format.
+type resultWriter struct {
+	w      io.Writer
+	format string
+	multi  bool // print names of inputs in text
+	all    []jsonTranscript
+}
+
+func newResultWriter(w io.Writer, format string, multi bool) *resultWriter {
+	return &resultWriter{w: w, format: format, multi: multi, all: []jsonTranscript{}}
+}
+
+// Write writes results of an input. err is the error of recognition, which
+// is recorded in json and jsonl.
+func (o *resultWriter) Write(name string, rs []recaius.AsrResult, err error) error {
+	t := recaius.NewTranscript(rs...)
+	switch o.format {
+	case "json":
+		o.all = append(o.all, newJSONTranscript(name, t, err))
+		return nil
+	case "jsonl":
+		return json.NewEncoder(o.w).Encode(newJSONTranscript(name, t, err))
+	}
+	if err != nil {
+		return nil
+	}
+	if o.multi && o.format == "text" {
+		if _, err := fmt.Fprintf(o.w, "==> %s <==\n", name); err != nil {
+			return err
+		}
+	}
+	return writeTranscript(o.w, o.format, name, t)
+}
+
+// writeTranscript writes a transcript of an input. json is a single object.
+func writeTranscript(w io.Writer, format string, name string, t *recaius.Transcript) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newJSONTranscript(name, t, nil))
+	case "jsonl":
+		return json.NewEncoder(w).Encode(newJSONTranscript(name, t, nil))
+	case "srt":
+		return recaius.WriteSRT(w, recaius.BuildTranscriptCues(t, recaius.SubtitleOptions{}))
+	case "vtt":
+		return recaius.WriteVTT(w, recaius.BuildTranscriptCues(t, recaius.SubtitleOptions{}))
+	}
+	_, err := fmt.Fprintln(w, t.Text())
+	return err
+}
+
+// Close writes the whole output of json.
+func (o *resultWriter) Close() error {
+	if o.format != "json" {
+		return nil
+	}
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(o.all)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hi6tanaka/recaius"
+)
+
+func runTranscribe(args []string) error {
+	fs := flag.NewFlagSet("transcribe", flag.ContinueOnError)
+	var cf clientFlags
+	cf.registerAsr(fs)
+	resultType := fs.String("result-type", "", "one_best or nbest (default nbest for json, jsonl, srt and vtt, one_best for text)")
+	chunk := fs.Duration("chunk", time.Second, "duration of audio sent at once")
+	format := fs.String("format", "text", "output format: text, json, jsonl, srt or vtt")
+	audioType := fs.String("audio-type", "audio/x-linear", "audio type of raw input. WAV files must match it")
+	energy := fs.Int64("energy", 0, "energy threshold")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: recaius transcribe [flags] [FILE...]")
+		fmt.Fprintln(os.Stderr, "\nFILE is WAV or raw audio. stdin is read if FILE is - or omitted.")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	if !validFormat(*format) {
+		return usageError("unknown format %q", *format)
+	}
+	if (*format == "srt" || *format == "vtt") && len(inputs) > 1 {
+		return usageError("%s accepts only one input", *format)
+	}
+	switch *resultType {
+	case "":
+		*resultType = "one_best"
+		if *format != "text" {
+			*resultType = "nbest"
+		}
+	case "one_best":
+		if *format == "srt" || *format == "vtt" {
+			return usageError("%s needs word timings of -result-type nbest", *format)
+		}
+	case "nbest":
+	default:
+		return usageError("unknown result type %q", *resultType)
+	}
+	f, err := recaius.AudioFormatOf(*audioType)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if *chunk <= 0 {
+		return usageError("chunk must be positive")
+	}
+
+	auth, logout, err := cf.login()
+	if err != nil {
+		return err
+	}
+	defer logout()
+	asr := recaius.NewAsrWithConfig(auth, &recaius.AsrConfig{
+		AudioType:       *audioType,
+		ResultType:      *resultType,
+		ModelID:         cf.model,
+		EnergyThreshold: *energy,
+		ChunkDuration:   int64(*chunk / time.Millisecond),
+	})
+	defer asr.Close()
+
+	out := newResultWriter(os.Stdout, *format, len(inputs) > 1)
+	failed := 0
+	for _, in := range inputs {
+		rs, err := transcribe(asr, in, f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", in, err)
+			failed++
+		}
+		if err := out.Write(in, rs, err); err != nil {
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &exitError{exitFailure, fmt.Errorf("%d of %d inputs failed", failed, len(inputs))}
+	}
+	return nil
+}
+
+// transcribe recognizes a file, or stdin if name is "-".
+func transcribe(asr *recaius.Asr, name string, f recaius.AudioFormat) ([]recaius.AsrResult, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	audio, err := openAudio(r, f)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := asr.Session()
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Close()
+	if _, err := io.Copy(sess, audio); err != nil {
+		return nil, err
+	}
+	return sess.FlushWait()
+}
+
+// openAudio returns the data of WAV, or r itself for raw audio.
+// A WAV must be in format f.
+func openAudio(r io.Reader, f recaius.AudioFormat) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(4); string(head) != "RIFF" {
+		return br, nil
+	}
+	w, err := recaius.ReadWav(br)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWav(w, f); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(w.Data), nil
+}
+
+// checkWav returns an error unless w is in format f.
+func checkWav(w *recaius.Wav, f recaius.AudioFormat) error {
+	if w.Format.SampleRate != f.SampleRate || w.Format.BitsPerSample != f.BitsPerSample || w.Format.Channels != f.Channels {
+		return fmt.Errorf("%dHz %dbit %dch WAV does not match the audio type (%dHz %dbit %dch)",
+			w.Format.SampleRate, w.Format.BitsPerSample, w.Format.Channels,
+			f.SampleRate, f.BitsPerSample, f.Channels)
+	}
+	return nil
+}
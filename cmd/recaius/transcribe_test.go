@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hi6tanaka/recaius"
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+// runCommand runs a command with stdin, and returns its stdout and exit code.
+func runCommand(t *testing.T, run func([]string) error, args []string, stdin []byte) (string, int) {
+	in, err := ioutil.TempFile("", "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(in.Name())
+	defer in.Close()
+	if _, err := in.Write(stdin); err != nil {
+		t.Fatal(err)
+	}
+	in.Seek(0, 0)
+	out, err := ioutil.TempFile("", "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	stdin0, stdout0 := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = in, out
+	code := exitCode(run(args))
+	os.Stdin, os.Stdout = stdin0, stdout0
+
+	data, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), code
+}
+
+func TestTranscribe(t *testing.T) {
+	a := newAuthTest(t)
+	defer a.Close()
+	a.srv.DefaultTranscript("こんにちは")
+	good := filepath.Join(a.dir, "good.wav")
+	writeWav(t, good, recaius.LinearPCM16k, make([]byte, 32000))
+	stereo := filepath.Join(a.dir, "stereo.wav")
+	writeWav(t, stereo, recaius.AudioFormat{SampleRate: 44100, BitsPerSample: 16, Channels: 2, BlockAlign: 4}, make([]byte, 176400))
+
+	if out, code := runCommand(t, runTranscribe, append(a.flags, good), nil); code != exitOK || out != "こんにちは\n" {
+		t.Fatalf("transcribe a file: %d %q", code, out)
+	}
+	// raw audio from stdin
+	if out, code := runCommand(t, runTranscribe, append(a.flags, "-format", "srt"), make([]byte, 32000)); code != exitOK || out != "1\n00:00:00,000 --> 00:00:01,000\nこんにちは\n\n" {
+		t.Fatalf("transcribe stdin: %d %q", code, out)
+	}
+	if out, code := runCommand(t, runTranscribe, append(a.flags, good, stereo), nil); code != exitFailure || out != "==> "+good+" <==\nこんにちは\n" {
+		t.Fatalf("transcribe a mismatched file: %d %q", code, out)
+	}
+	if _, code := runCommand(t, runTranscribe, append(a.flags, "-format", "srt", "-result-type", "one_best", good), nil); code != exitUsage {
+		t.Fatal("srt without word timings:", code)
+	}
+
+	a.srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointCreate, Status: 500})
+	if _, code := runCommand(t, runTranscribe, append(a.flags, good), nil); code != exitFailure {
+		t.Fatal("transcribe with a server error:", code)
+	}
+}
@@ -1,4 +1,5 @@
 package recaius
 
-const tokenURL = "https://api.recaius.jp/auth/v2/tokens"
-const asrURL = "https://api.recaius.jp/asr/v2"
+const baseURL = "https://api.recaius.jp"
+const tokenPath = "/auth/v2/tokens"
+const asrPath = "/asr/v2"
@@ -0,0 +1,192 @@
+// Package eval computes character and word error rates of recognition
+// results against reference transcripts.
+package eval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is an edit operation of an alignment.
+type Op int
+
+const (
+	Match Op = iota
+	Substitution
+	Insertion
+	Deletion
+)
+
+func (op Op) String() string {
+	switch op {
+	case Match:
+		return "="
+	case Substitution:
+		return "S"
+	case Insertion:
+		return "I"
+	case Deletion:
+		return "D"
+	}
+	return "?"
+}
+
+// Edit is an aligned pair of reference and hypothesis tokens.
+// Ref is empty for Insertion, and Hyp is empty for Deletion.
+type Edit struct {
+	Op  Op
+	Ref string
+	Hyp string
+}
+
+// Result is an alignment of a hypothesis to a reference.
+type Result struct {
+	Edits         []Edit
+	Matches       int
+	Substitutions int
+	Insertions    int
+	Deletions     int
+}
+
+// RefLen returns the number of reference tokens.
+func (r Result) RefLen() int {
+	return r.Matches + r.Substitutions + r.Deletions
+}
+
+func (r Result) Errors() int {
+	return r.Substitutions + r.Insertions + r.Deletions
+}
+
+// ErrorRate returns (S+I+D)/N. It is 0 for empty reference and hypothesis.
+func (r Result) ErrorRate() float64 {
+	return errorRate(r.Errors(), r.RefLen())
+}
+
+func errorRate(errors int, n int) float64 {
+	if n == 0 {
+		if errors == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(errors) / float64(n)
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%.2f%% (N=%d S=%d I=%d D=%d)",
+		r.ErrorRate()*100, r.RefLen(), r.Substitutions, r.Insertions, r.Deletions)
+}
+
+// Align aligns tokens by minimum edit distance.
+func Align(ref []string, hyp []string) Result {
+	// d[i][j]: distance between ref[:i] and hyp[:j]
+	d := make([][]int, len(ref)+1)
+	for i := range d {
+		d[i] = make([]int, len(hyp)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ref); i++ {
+		for j := 1; j <= len(hyp); j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j-1]+cost, d[i-1][j]+1, d[i][j-1]+1)
+		}
+	}
+
+	var r Result
+	var edits []Edit
+	for i, j := len(ref), len(hyp); i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && ref[i-1] == hyp[j-1] && d[i][j] == d[i-1][j-1]:
+			edits = append(edits, Edit{Match, ref[i-1], hyp[j-1]})
+			r.Matches++
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			edits = append(edits, Edit{Substitution, ref[i-1], hyp[j-1]})
+			r.Substitutions++
+			i, j = i-1, j-1
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			edits = append(edits, Edit{Deletion, ref[i-1], ""})
+			r.Deletions++
+			i--
+		default:
+			edits = append(edits, Edit{Insertion, "", hyp[j-1]})
+			r.Insertions++
+			j--
+		}
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	r.Edits = edits
+	return r
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// CER aligns normalized texts character by character.
+func CER(ref string, hyp string) Result {
+	return Align(chars(Normalize(ref)), chars(Normalize(hyp)))
+}
+
+// WER aligns texts word by word. Words are separated by spaces.
+func WER(ref string, hyp string) Result {
+	return Align(words(ref), words(hyp))
+}
+
+func chars(s string) []string {
+	var cs []string
+	for _, r := range s {
+		cs = append(cs, string(r))
+	}
+	return cs
+}
+
+func words(s string) []string {
+	var ws []string
+	for _, w := range strings.Fields(s) {
+		if w = Normalize(w); w != "" {
+			ws = append(ws, w)
+		}
+	}
+	return ws
+}
+
+// Corpus aggregates results over utterances or files.
+type Corpus struct {
+	Results []Result
+}
+
+func (c *Corpus) Add(r Result) {
+	c.Results = append(c.Results, r)
+}
+
+// Total sums edit counts of all results.
+func (c *Corpus) Total() Result {
+	var t Result
+	for _, r := range c.Results {
+		t.Matches += r.Matches
+		t.Substitutions += r.Substitutions
+		t.Insertions += r.Insertions
+		t.Deletions += r.Deletions
+	}
+	return t
+}
+
+// ErrorRate returns the corpus-level error rate, weighted by reference length.
+func (c *Corpus) ErrorRate() float64 {
+	return c.Total().ErrorRate()
+}
@@ -0,0 +1,39 @@
+package eval
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"ＡＢＣ　１２３":    "abc123",
+		"ｶﾞｷﾞｸﾞﾊﾟﾋﾟ": "がぎぐぱぴ",
+		"テスト、です。":    "てすとです",
+	} {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCER(t *testing.T) {
+	r := CER("今日はいい天気です", "今日わいい天気でした")
+	if r.Substitutions != 2 || r.Insertions != 1 || r.Deletions != 0 || r.RefLen() != 9 {
+		t.Fatal("unexpected alignment:", r, r.Edits)
+	}
+	if r := CER("ＡＩ、テスト", "aiてすと"); r.Errors() != 0 {
+		t.Fatal("normalization not applied:", r.Edits)
+	}
+}
+
+func TestWER(t *testing.T) {
+	r := WER("the quick brown fox", "the brown fax jumps")
+	if r.Substitutions != 3 || r.Insertions != 0 || r.Deletions != 0 {
+		t.Fatal("unexpected alignment:", r, r.Edits)
+	}
+
+	var c Corpus
+	c.Add(r)
+	c.Add(WER("Hello, world", "hello world"))
+	if rate := c.ErrorRate(); rate != 0.5 {
+		t.Fatal("unexpected corpus error rate:", rate)
+	}
+}
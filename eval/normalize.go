@@ -0,0 +1,23 @@
+package eval
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/hi6tanaka/recaius"
+)
+
+// Normalize folds text for scoring: width is normalized and katakana is
+// converted to hiragana by recaius.ToHiragana, letters to lower case.
+// Spaces and punctuation are removed.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range recaius.ToHiragana(s) {
+		r = unicode.ToLower(r)
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
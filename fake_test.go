@@ -0,0 +1,510 @@
+package recaius
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+func fakeAsr(t *testing.T, srv *recaiustest.Server, config *AsrConfig) *Asr {
+	auth := &Auth{
+		SpeechRecogJa: &ServiceInfo{ServiceId: "id", Password: "pass"},
+		BaseURL:       srv.URL,
+		AutoLogin:     true,
+	}
+	if err := auth.Login(); err != nil {
+		t.Fatal("login failed:", err)
+	}
+	config.PollingInterval = 10
+	return NewAsrWithConfig(auth, config)
+}
+
+func recognizeFake(asr *Asr, audio []byte) ([]AsrResult, error) {
+	sess, err := asr.Session()
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Close()
+	if _, err := sess.Write(audio); err != nil {
+		return nil, err
+	}
+	return sess.FlushWait()
+}
+
+func TestFakeSession(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.Script(recaiustest.Utterance("こんにちは",
+		recaiustest.Word{Str: "こんにちは", Yomi: "コンニチワ", Confidence: 0.9, Begin: 100, End: 600})...)
+
+	for _, resultType := range []string{"one_best", "nbest"} {
+		asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1, ResultType: resultType})
+		rs, err := recognizeFake(asr, make([]byte, 64000))
+		if err != nil {
+			t.Fatal(resultType, "recognize failed:", err)
+		}
+		tr := NewTranscript(rs...)
+		if tr.Text() != "こんにちは" {
+			t.Fatal(resultType, "unexpected text:", tr.Text())
+		}
+		if resultType == "nbest" {
+			words := tr.Words()
+			if len(words) != 1 || words[0].Yomi != "コンニチワ" || words[0].EndTime() != 600*time.Millisecond {
+				t.Fatal("unexpected words:", words)
+			}
+		}
+		if n := srv.Voices(); n != 0 {
+			t.Fatal("voices not deleted:", n)
+		}
+	}
+}
+
+func TestFakeStream(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.Script(recaiustest.Utterance("おはよう")...)
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1})
+
+	sess, err := asr.Stream()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	go func() {
+		sess.ReadFrom(bytes.NewReader(make([]byte, 64000)))
+		sess.Close()
+	}()
+	tr := NewTranscript()
+	tr.Watch(sess.Response())
+	if tr.Text() != "おはよう" {
+		t.Fatal("unexpected text:", tr.Text())
+	}
+}
+
+func TestFakeFaults(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.Script(recaiustest.Utterance("こんにちは")...)
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1})
+
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointSend, Status: 500, Count: 1})
+	if _, err := recognizeFake(asr, make([]byte, 32000)); err == nil {
+		t.Fatal("server error is not returned")
+	} else if e, ok := err.(ResponseError); !ok || e.Code != 500 {
+		t.Fatal("unexpected error:", err)
+	}
+
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointCreate, Status: 401, Count: 1})
+	if _, err := recognizeFake(asr, make([]byte, 32000)); err == nil {
+		t.Fatal("unauthorized is not returned")
+	}
+
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointResults, DropResults: true, Count: 1})
+	rs, err := recognizeFake(asr, make([]byte, 32000))
+	if err != nil {
+		t.Fatal("recognize failed:", err)
+	}
+	if text := NewTranscript(rs...).Text(); text != "" {
+		t.Fatal("results are not dropped:", text)
+	}
+
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointFlush, Latency: 100 * time.Millisecond, Count: 1})
+	start := time.Now()
+	rs, err = recognizeFake(asr, make([]byte, 32000))
+	if err != nil {
+		t.Fatal("recognize failed:", err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatal("latency is not injected:", d)
+	}
+	if text := NewTranscript(rs...).Text(); text != "こんにちは" {
+		t.Fatal("fault applied more than Count:", text)
+	}
+	if n := srv.Voices(); n != 0 {
+		t.Fatal("voices not deleted:", n)
+	}
+}
+
+func TestFakeStateMachine(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("いろは にほへと")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1, ResultType: "nbest", ChunkDuration: 500})
+
+	sess, err := asr.Stream()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	sess.Write(make([]byte, 48000)) // 3 chunks
+	var types []string
+	tr := NewTranscript()
+	// partials come before the flush
+	for _, want := range []string{"SOS", "TMP_RESULT"} {
+		r := <-sess.Response()
+		if r.Type != want {
+			t.Fatal("unexpected result before flush:", r.Type, r.Err)
+		}
+		types = append(types, r.Type)
+		tr.Add(r)
+	}
+	go sess.Close()
+	for r := range sess.Response() {
+		if r.Err != nil {
+			t.Fatal("error:", r.Err)
+		}
+		types = append(types, r.Type)
+		tr.Add(r)
+	}
+	if got := strings.Join(types, " "); got != "SOS TMP_RESULT TMP_RESULT TMP_RESULT RESULT" {
+		t.Fatal("unexpected results:", got)
+	}
+	words := tr.Words()
+	if len(words) != 2 || words[1].Str != "にほへと" || words[1].EndTime() != 1500*time.Millisecond {
+		t.Fatal("unexpected words:", words)
+	}
+
+	var voiceIDs []int64
+	for _, req := range srv.Requests() {
+		if req.Token == "" && req.Endpoint != recaiustest.EndpointLogin {
+			t.Fatal("request without token:", req)
+		}
+		switch req.Endpoint {
+		case recaiustest.EndpointSend:
+			if strings.Join(req.Fields, ",") != "voice_id,voice" || req.AudioSize != 16000 {
+				t.Fatal("unexpected send:", req)
+			}
+			fallthrough
+		case recaiustest.EndpointFlush:
+			voiceIDs = append(voiceIDs, req.VoiceID)
+		}
+	}
+	if fmt.Sprint(voiceIDs) != "[1 2 3 4]" {
+		t.Fatal("unexpected voice_id order:", voiceIDs)
+	}
+}
+
+func TestFakeFingerprint(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	audio := makeTone(time.Second, 1000)
+	srv.Transcribe(recaiustest.Fingerprint(audio), "ラ")
+	srv.DefaultTranscript("無音")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1})
+
+	for _, c := range []struct {
+		audio []byte
+		want  string
+	}{{audio, "ラ"}, {make([]byte, len(audio)), "無音"}} {
+		rs, err := recognizeFake(asr, c.audio)
+		if err != nil {
+			t.Fatal("recognize failed:", err)
+		}
+		if text := NewTranscript(rs...).Text(); text != c.want {
+			t.Fatal("unexpected text:", text)
+		}
+	}
+}
+
+func TestFakeToken(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("こんにちは")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1})
+
+	srv.ExpireTokens()
+	if _, err := recognizeFake(asr, make([]byte, 32000)); err == nil {
+		t.Fatal("expired token is accepted")
+	} else if e, ok := err.(ResponseError); !ok || e.Code != 401 {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := asr.auth.Login(); err != nil {
+		t.Fatal("login failed:", err)
+	}
+	if _, err := recognizeFake(asr, make([]byte, 32000)); err != nil {
+		t.Fatal("recognize failed after login:", err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("こんにちは 世界")
+	recognize := func(baseURL string, transport http.RoundTripper) string {
+		auth := &Auth{
+			SpeechRecogJa: &ServiceInfo{ServiceId: "id", Password: "secret"},
+			BaseURL:       baseURL,
+			HTTPClient:    &http.Client{Transport: transport},
+		}
+		if err := auth.Login(); err != nil {
+			t.Fatal("login failed:", err)
+		}
+		defer auth.Logout()
+		asr := NewAsrWithConfig(auth, &AsrConfig{ModelID: 1, ResultType: "nbest", PollingInterval: 10})
+		rs, err := recognizeFake(asr, make([]byte, 48000))
+		if err != nil {
+			t.Fatal("recognize failed:", err)
+		}
+		return NewTranscript(rs...).Text()
+	}
+
+	rec := recaiustest.NewRecorder(nil)
+	want := recognize(srv.URL, rec)
+	data, err := json.Marshal(rec.Cassette())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) || bytes.Contains(data, []byte("token-")) {
+		t.Fatal("secrets are recorded:", string(data))
+	}
+
+	// the same voice can be replayed without the server
+	srv.Close()
+	p := recaiustest.NewReplayer(rec.Cassette())
+	if got := recognize("http://recaius.invalid", p); got != want {
+		t.Fatalf("replayed %q, recorded %q", got, want)
+	}
+	if rest := p.Unused(); len(rest) != 0 {
+		t.Fatal("interactions not replayed:", rest)
+	}
+}
+
+func TestFakeSendFailure(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("こんにちは")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1})
+
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointSend, Status: 500, Count: 1})
+	sess, err := asr.Session()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	if n, err := sess.Write(make([]byte, 48000)); err == nil || n != 0 {
+		t.Fatal("unexpected write:", n, err)
+	}
+	if err := sess.Close(); err != nil {
+		t.Fatal("close error:", err)
+	}
+
+	stream, err := asr.Stream()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	response := stream.Response()
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointSend, Status: 500, Count: 1})
+	if _, err := stream.Write(make([]byte, 32000)); err == nil {
+		t.Fatal("send error is not returned")
+	}
+	stream.Close()
+	for range response {
+	}
+
+	// failed audio is neither sent again nor flushed
+	for _, req := range srv.Requests() {
+		if req.Endpoint == recaiustest.EndpointSend && req.Status == 200 || req.Endpoint == recaiustest.EndpointFlush {
+			t.Fatal("unexpected request after failure:", req)
+		}
+	}
+	if n := srv.Voices(); n != 0 {
+		t.Fatal("voices not deleted:", n)
+	}
+}
+
+func TestFakeStreamWatch(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("こんにちは")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1})
+
+	sess, err := asr.Stream()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	go sess.StartWatch()
+	go func() {
+		sess.Write(make([]byte, 64000))
+		sess.Close()
+	}()
+	tr := NewTranscript()
+	tr.Watch(sess.Response())
+	if tr.Text() != "こんにちは" {
+		t.Fatal("unexpected text:", tr.Text())
+	}
+}
+
+func TestFakePushToTalk(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.TranscribeChunks(1, "はい")
+	srv.TranscribeChunks(2, "いいえ")
+	config := &AsrConfig{ModelID: 1, PhshToTalk: true}
+	asr := fakeAsr(t, srv, config)
+	if config.PushToTalk || !asr.config.PushToTalk {
+		t.Fatal("deprecated PhshToTalk is not mapped on a copy")
+	}
+
+	ptt, err := asr.PushToTalk()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	defer ptt.Close()
+	if err := ptt.Send(make([]byte, 32000)); err != ErrNotPressed {
+		t.Fatal("send before press:", err)
+	}
+	for _, c := range []struct {
+		audio int
+		want  string
+	}{{32000, "はい"}, {64000, "いいえ"}} {
+		if err := ptt.Press(); err != nil {
+			t.Fatal("press error:", err)
+		}
+		if err := ptt.Press(); err != ErrPressed {
+			t.Fatal("second press:", err)
+		}
+		if _, err := ptt.Write(make([]byte, c.audio)); err != nil {
+			t.Fatal("write error:", err)
+		}
+		rs, err := ptt.Release()
+		if err != nil {
+			t.Fatal("release error:", err)
+		}
+		if len(rs) != 1 || rs[0].OneBest.Str != c.want {
+			t.Fatal("unexpected results:", rs)
+		}
+	}
+	if _, err := ptt.Write(make([]byte, 32000)); err != ErrNotPressed {
+		t.Fatal("write after release:", err)
+	}
+
+	// release without audio makes no request
+	before := len(srv.Requests())
+	ptt.Press()
+	if rs, err := ptt.Release(); rs != nil || err != nil {
+		t.Fatal("unexpected empty release:", rs, err)
+	}
+	if n := len(srv.Requests()); n != before {
+		t.Fatal("empty release makes requests:", srv.Requests()[before:])
+	}
+}
+
+func TestFakeStreamFlushes(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.TranscribeChunks(1, "いち")
+	srv.TranscribeChunks(2, "さん よん")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1, ResultType: "nbest", KeepOpen: true})
+
+	sess, err := asr.Stream()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	response := sess.Response()
+	go sess.StartWatch()
+	closed := make(chan error)
+	go func() {
+		sess.Write(make([]byte, 32000)) // 1s
+		sess.Flush()
+		sess.Write(make([]byte, 64000)) // 2s
+		closed <- sess.Close()
+	}()
+	tr := NewTranscript()
+	tr.Watch(response)
+	if err := <-closed; err != nil {
+		t.Fatal("close error:", err)
+	}
+	if tr.Text() != "いち\nさん よん" {
+		t.Fatal("unexpected text:", tr.Text())
+	}
+	words := tr.Words()
+	if len(words) != 3 {
+		t.Fatal("unexpected words:", words)
+	}
+	// the second cycle starts at 1s
+	if words[1].BeginTime() != time.Second || words[2].BeginTime() != 2*time.Second || words[2].EndTime() != 3*time.Second {
+		t.Fatal("unexpected word timings:", words[1].BeginTime(), words[2].BeginTime(), words[2].EndTime())
+	}
+	if n := srv.Voices(); n != 0 {
+		t.Fatal("voices not deleted:", n)
+	}
+}
+
+func TestFakeStreamFlushWatch(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("こんにちは")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1})
+
+	sess, err := asr.Stream()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	tr := NewTranscript()
+	watched := make(chan struct{})
+	go func() {
+		tr.Watch(sess.Response())
+		close(watched)
+	}()
+	sess.Write(make([]byte, 32000))
+	sess.Flush()
+	done := make(chan struct{})
+	go func() {
+		sess.StartWatch()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartWatch does not return after the flush")
+	}
+	<-watched
+	if tr.Text() != "こんにちは" {
+		t.Fatal("unexpected text:", tr.Text())
+	}
+	if err := sess.Close(); err != nil {
+		t.Fatal("close error:", err)
+	}
+	if n := srv.Voices(); n != 0 {
+		t.Fatal("voices not deleted:", n)
+	}
+}
+
+func TestFakeUtterances(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.TranscribeChunks(4, "いち に\nさん よん")
+	asr := fakeAsr(t, srv, &AsrConfig{ModelID: 1, ResultType: "nbest", ChunkDuration: 500})
+
+	sess, err := asr.Stream()
+	if err != nil {
+		t.Fatal("create session error:", err)
+	}
+	go func() {
+		sess.Write(make([]byte, 64000)) // 4 chunks
+		sess.Close()
+	}()
+	var types []string
+	tr := NewTranscript()
+	for r := range sess.Response() {
+		if r.Err != nil {
+			t.Fatal("error:", r.Err)
+		}
+		types = append(types, r.Type)
+		tr.Add(r)
+	}
+	if got := strings.Join(types, " "); got != "SOS TMP_RESULT RESULT SOS TMP_RESULT RESULT" {
+		t.Fatal("unexpected results:", got)
+	}
+	if tr.Text() != "いち に\nさん よん" {
+		t.Fatal("unexpected text:", tr.Text())
+	}
+	// the second utterance starts at the third chunk
+	words := tr.Words()
+	if len(words) != 4 || words[1].EndTime() != time.Second || words[2].BeginTime() != time.Second || words[3].EndTime() != 2*time.Second {
+		t.Fatal("unexpected words:", words)
+	}
+}
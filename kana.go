@@ -0,0 +1,218 @@
+package recaius
+
+import "strings"
+
+// ToHiragana converts katakana in s to hiragana. Half-width katakana is
+// converted too.
+func ToHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - 'ァ' + 'ぁ'
+		}
+		return r
+	}, NormalizeWidth(s))
+}
+
+// ToKatakana converts hiragana in s to katakana. Half-width katakana is
+// converted to full-width.
+func ToKatakana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ぁ' && r <= 'ゖ' {
+			return r - 'ぁ' + 'ァ'
+		}
+		return r
+	}, NormalizeWidth(s))
+}
+
+// RomajiOptions controls romanization.
+type RomajiOptions struct {
+	Macron bool // long vowels as ā, ī, ū, ē, ō instead of repeating vowels
+}
+
+// ToRomaji converts kana in s to Hepburn romaji. Since s has no word
+// boundaries, は, へ and を are read as ha, he and o. Use
+// AsrNBestElement.Romaji to read particles as wa and e.
+func ToRomaji(s string) string {
+	return RomajiOptions{}.Convert(s)
+}
+
+var romajiTable = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n", "ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o",
+	"ゃ": "ya", "ゅ": "yu", "ょ": "yo", "ゎ": "wa",
+
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo", "ふゅ": "fyu",
+	"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du", "でゅ": "dyu",
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo", "いぇ": "ye",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+	"しぇ": "she", "じぇ": "je", "ちぇ": "che",
+	"つぁ": "tsa", "つぃ": "tsi", "つぇ": "tse", "つぉ": "tso",
+}
+
+func init() {
+	// yōon, e.g. きゃ kya, しゃ sha
+	for _, base := range []string{"き", "ぎ", "に", "ひ", "び", "ぴ", "み", "り"} {
+		c := strings.TrimSuffix(romajiTable[base], "i")
+		romajiTable[base+"ゃ"] = c + "ya"
+		romajiTable[base+"ゅ"] = c + "yu"
+		romajiTable[base+"ょ"] = c + "yo"
+	}
+	for base, c := range map[string]string{"し": "sh", "ち": "ch", "じ": "j", "ぢ": "j"} {
+		romajiTable[base+"ゃ"] = c + "a"
+		romajiTable[base+"ゅ"] = c + "u"
+		romajiTable[base+"ょ"] = c + "o"
+	}
+}
+
+var macrons = map[byte]string{'a': "ā", 'i': "ī", 'u': "ū", 'e': "ē", 'o': "ō"}
+
+// Convert converts kana in s to Hepburn romaji. Other characters are kept.
+func (o RomajiOptions) Convert(s string) string {
+	rs := []rune(ToHiragana(s))
+	var out []string
+	sokuon := false
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		unit, n := romajiUnit(rs[i:])
+		i += n
+		switch {
+		case r == 'っ':
+			sokuon = true
+			continue
+		case r == 'ー':
+			out = o.lengthen(out)
+			continue
+		case unit == "":
+			out = append(out, string(r))
+			sokuon = false
+			continue
+		}
+
+		last := ""
+		if len(out) > 0 {
+			last = out[len(out)-1]
+		}
+		if o.Macron && (unit == "u" && (strings.HasSuffix(last, "o") || strings.HasSuffix(last, "u")) ||
+			unit == "o" && strings.HasSuffix(last, "o")) {
+			out = o.lengthen(out)
+			continue
+		}
+		if sokuon {
+			if strings.HasPrefix(unit, "ch") {
+				unit = "t" + unit
+			} else if !isRomajiVowel(unit[0]) {
+				unit = unit[:1] + unit
+			}
+			sokuon = false
+		}
+		if last == "n" && (isRomajiVowel(unit[0]) || unit[0] == 'y') {
+			unit = "'" + unit
+		}
+		out = append(out, unit)
+	}
+	return strings.Join(out, "")
+}
+
+// romaji of the longest kana unit at the head of rs, and its length
+func romajiUnit(rs []rune) (string, int) {
+	if len(rs) >= 2 {
+		if r, ok := romajiTable[string(rs[:2])]; ok {
+			return r, 2
+		}
+	}
+	return romajiTable[string(rs[0])], 1
+}
+
+// lengthen the vowel at the end of the output
+func (o RomajiOptions) lengthen(out []string) []string {
+	if len(out) == 0 {
+		return out
+	}
+	last := out[len(out)-1]
+	v := last[len(last)-1]
+	if !isRomajiVowel(v) {
+		return out
+	}
+	if o.Macron {
+		out[len(out)-1] = last[:len(last)-1] + macrons[v]
+	} else {
+		out[len(out)-1] = last + string(v)
+	}
+	return out
+}
+
+func isRomajiVowel(c byte) bool {
+	return strings.IndexByte("aiueo", c) >= 0
+}
+
+// reading of a word, with particles は, へ and を read as pronounced
+func (w AsrNBestWord) reading() string {
+	switch w.Str {
+	case "は":
+		return "わ"
+	case "へ":
+		return "え"
+	case "を":
+		return "お"
+	}
+	if w.Yomi != "" {
+		return w.Yomi
+	}
+	if isKana(w.Str) {
+		return w.Str
+	}
+	return ""
+}
+
+func isKana(s string) bool {
+	for _, r := range s {
+		if !(r >= 'ぁ' && r <= 'ゖ' || r >= 'ァ' && r <= 'ヺ' || r == 'ー') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Kana returns the reading of the hypothesis in hiragana. Words without
+// readings are kept as they are.
+func (e AsrNBestElement) Kana() string {
+	var b strings.Builder
+	for _, w := range e.Words {
+		if w.Yomi != "" {
+			b.WriteString(ToHiragana(w.Yomi))
+		} else {
+			b.WriteString(ToHiragana(w.Str))
+		}
+	}
+	return b.String()
+}
+
+// Romaji returns the reading of the hypothesis in Hepburn romaji, one word
+// separated by a space. Particles は, へ and を are read as wa, e and o.
+func (e AsrNBestElement) Romaji(opt RomajiOptions) string {
+	var ws []string
+	for _, w := range e.Words {
+		r := w.reading()
+		if r == "" {
+			r = w.Str
+		}
+		if r = opt.Convert(r); r != "" {
+			ws = append(ws, r)
+		}
+	}
+	return strings.Join(ws, " ")
+}
@@ -0,0 +1,49 @@
+package recaius
+
+import "testing"
+
+func TestToRomaji(t *testing.T) {
+	for in, want := range map[string]string{
+		"きょうと":   "kyouto",
+		"ざっし":    "zasshi",
+		"まっちゃ":   "matcha",
+		"コーヒー":   "koohii",
+		"しんいち":   "shin'ichi",
+		"ティーシャツ": "tiishatsu",
+		"ｶﾞｯｺｳ":  "gakkou",
+	} {
+		if got := ToRomaji(in); got != want {
+			t.Errorf("ToRomaji(%q) = %q, want %q", in, got, want)
+		}
+	}
+	macron := RomajiOptions{Macron: true}
+	for in, want := range map[string]string{
+		"とうきょう": "tōkyō",
+		"コーヒー":  "kōhī",
+		"おおさか":  "ōsaka",
+	} {
+		if got := macron.Convert(in); got != want {
+			t.Errorf("Convert(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNBestReading(t *testing.T) {
+	e := AsrNBestElement{Str: "私は駅へ本を", Words: []AsrNBestWord{
+		{Str: "私", Yomi: "ワタシ"},
+		{Str: "は", Yomi: "ハ"},
+		{Str: "駅", Yomi: "エキ"},
+		{Str: "へ", Yomi: "ヘ"},
+		{Str: "本", Yomi: "ホン"},
+		{Str: "を", Yomi: "ヲ"},
+	}}
+	if s := e.Kana(); s != "わたしはえきへほんを" {
+		t.Fatal("unexpected kana:", s)
+	}
+	if s := e.Romaji(RomajiOptions{}); s != "watashi wa eki e hon o" {
+		t.Fatal("unexpected romaji:", s)
+	}
+	if s := ToKatakana("ひらがな"); s != "ヒラガナ" {
+		t.Fatal("unexpected katakana:", s)
+	}
+}
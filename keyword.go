@@ -0,0 +1,292 @@
+package recaius
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Keyword is a phrase to spot. Readings are matched against AsrNBestWord.Yomi
+// allowing small differences.
+type Keyword struct {
+	Name     string   // label of events
+	Phrases  []string // surface forms, e.g. 解約
+	Readings []string // in kana, e.g. カイヤク
+}
+
+// KeywordEvent is emitted when a keyword is spotted.
+type KeywordEvent struct {
+	Keyword    string // Keyword.Name
+	Phrase     string // matched phrase or reading
+	Text       string // text of the result
+	Final      bool   // spotted in a final result
+	Reading    bool   // matched by reading
+	Confidence float64
+	Time       time.Duration // of the first matched word from the start of the session, or the start of its utterance without word timings
+}
+
+// KeywordSpotter spots keywords in partial and final results, and in nbest
+// alternatives. A keyword spotted in a partial result does not fire again when
+// the result becomes final, unless it appears more times.
+type KeywordSpotter struct {
+	Keywords      []Keyword
+	MinConfidence float64 // nbest alternatives with lower confidence, including the best one, are ignored
+	MaxDistance   int     // edit distance allowed in reading match. default 1 for readings of 4 or more kana
+
+	mu    sync.Mutex
+	fired map[string]int // times fired in the current utterance
+}
+
+func NewKeywordSpotter(keywords ...Keyword) *KeywordSpotter {
+	return &KeywordSpotter{Keywords: keywords}
+}
+
+// Watch spots keywords in results from ch, e.g. AsrStreamSession.Response.
+// The returned channel is closed when ch is closed.
+func (k *KeywordSpotter) Watch(ch <-chan AsrResult) <-chan KeywordEvent {
+	events := make(chan KeywordEvent)
+	go func() {
+		defer close(events)
+		for r := range ch {
+			for _, e := range k.Process(r) {
+				events <- e
+			}
+		}
+	}()
+	return events
+}
+
+// Process spots keywords in a result, and returns new events.
+func (k *KeywordSpotter) Process(r AsrResult) []KeywordEvent {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.fired == nil {
+		k.fired = map[string]int{}
+	}
+	if r.Err != nil {
+		return nil
+	}
+
+	var events []KeywordEvent
+	switch r.Type {
+	case "SOS":
+		k.fired = map[string]int{}
+	case "TMP_RESULT":
+		text := r.OneBest.Str
+		if r.NBest.Type != "" {
+			text = r.NBest.ResultTemp
+		}
+		var words []AsrNBestWord
+		if len(r.NBest.Result) > 0 {
+			words = r.NBest.Result[0].Words
+		}
+		for _, kw := range k.Keywords {
+			for _, m := range matchPhrases(kw, text) {
+				m.word = phraseWord(words, m)
+				events = k.fire(events, kw, m, KeywordEvent{Text: text, Time: matchTime(r, words, m)})
+			}
+		}
+	case "RESULT":
+		if r.NBest.Type == "" {
+			for _, kw := range k.Keywords {
+				for _, m := range matchPhrases(kw, r.OneBest.Str) {
+					events = k.fire(events, kw, m, KeywordEvent{Text: r.OneBest.Str, Final: true, Time: r.Offset})
+				}
+			}
+		}
+		for _, kw := range k.Keywords {
+			events = k.spotElements(events, kw, r)
+		}
+		k.fired = map[string]int{}
+	}
+	return events
+}
+
+// spot a keyword in the best of nbest elements it matches
+func (k *KeywordSpotter) spotElements(events []KeywordEvent, kw Keyword, r AsrResult) []KeywordEvent {
+	for _, e := range r.NBest.Result {
+		if e.Confidence < k.MinConfidence {
+			continue
+		}
+		matches := matchPhrases(kw, e.Str)
+		reading := false
+		if len(matches) == 0 {
+			matches = k.matchReadings(kw, e.Words)
+			reading = true
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		for _, m := range matches {
+			if !reading {
+				m.word = phraseWord(e.Words, m)
+			}
+			ev := KeywordEvent{Text: e.Str, Final: true, Reading: reading, Confidence: e.Confidence, Time: matchTime(r, e.Words, m)}
+			events = k.fire(events, kw, m, ev)
+		}
+		break
+	}
+	return events
+}
+
+// emit an event if the keyword appeared more times than fired
+func (k *KeywordSpotter) fire(events []KeywordEvent, kw Keyword, m keywordMatch, ev KeywordEvent) []KeywordEvent {
+	if m.count <= k.fired[kw.Name] {
+		return events
+	}
+	k.fired[kw.Name] = m.count
+	ev.Keyword = kw.Name
+	ev.Phrase = m.phrase
+	return append(events, ev)
+}
+
+// time of a match: the begin of its first word, or the start of the
+// utterance if unknown
+func matchTime(r AsrResult, words []AsrNBestWord, m keywordMatch) time.Duration {
+	if m.word >= 0 && m.word < len(words) {
+		return words[m.word].BeginTime()
+	}
+	return r.Offset
+}
+
+// phraseWord returns the index of the word where the phrase of m appears
+// the m.count-th time, or -1
+func phraseWord(words []AsrNBestWord, m keywordMatch) int {
+	// concatenate words, remembering which word each byte came from
+	var text strings.Builder
+	var owner []int
+	for i, w := range words {
+		f := foldKana(w.Str)
+		text.WriteString(f)
+		for range []byte(f) {
+			owner = append(owner, i)
+		}
+	}
+	t, p := text.String(), foldKana(m.phrase)
+	if p == "" {
+		return -1
+	}
+	pos := 0
+	for n := 0; n < m.count; n++ {
+		j := strings.Index(t[pos:], p)
+		if j < 0 {
+			return -1
+		}
+		if n == m.count-1 {
+			return owner[pos+j]
+		}
+		pos += j + len(p)
+	}
+	return -1
+}
+
+type keywordMatch struct {
+	phrase string
+	count  int // this is the count-th appearance in the text
+	word   int // index of the first matched word, or -1
+}
+
+func matchPhrases(kw Keyword, text string) []keywordMatch {
+	t := foldKana(text)
+	var ms []keywordMatch
+	for _, p := range kw.Phrases {
+		fp := foldKana(p)
+		if fp == "" {
+			continue
+		}
+		for i, n := 0, strings.Count(t, fp); i < n; i++ {
+			ms = append(ms, keywordMatch{phrase: p, count: len(ms) + 1, word: -1})
+		}
+		if len(ms) > 0 {
+			break
+		}
+	}
+	return ms
+}
+
+func (k *KeywordSpotter) matchReadings(kw Keyword, words []AsrNBestWord) []keywordMatch {
+	// concatenate readings, remembering which word each kana came from
+	var yomi []rune
+	var owner []int
+	for i, w := range words {
+		for _, r := range foldKana(w.Yomi) {
+			yomi = append(yomi, r)
+			owner = append(owner, i)
+		}
+	}
+	var ms []keywordMatch
+	for _, reading := range kw.Readings {
+		pattern := []rune(foldKana(reading))
+		if len(pattern) == 0 {
+			continue
+		}
+		maxDist := k.MaxDistance
+		if maxDist == 0 && len(pattern) >= 4 {
+			maxDist = 1
+		}
+		for _, end := range approxMatches(yomi, pattern, maxDist) {
+			start := end - len(pattern) + 1
+			if start < 0 {
+				start = 0
+			}
+			ms = append(ms, keywordMatch{phrase: reading, count: len(ms) + 1, word: owner[start]})
+		}
+		if len(ms) > 0 {
+			break
+		}
+	}
+	return ms
+}
+
+// approxMatches returns end positions of non-overlapping substrings of text
+// within maxDist edits of pattern.
+func approxMatches(text []rune, pattern []rune, maxDist int) []int {
+	// d[j]: distance between pattern[:j] and the best substring of text ending here
+	d := make([]int, len(pattern)+1)
+	for j := range d {
+		d[j] = j
+	}
+	var ends []int
+	for i, c := range text {
+		prev := d[0] // d[i-1][j-1]
+		d[0] = 0
+		for j := 1; j <= len(pattern); j++ {
+			cost := 1
+			if pattern[j-1] == c {
+				cost = 0
+			}
+			cur := min3(prev+cost, d[j]+1, d[j-1]+1)
+			prev, d[j] = d[j], cur
+		}
+		if d[len(pattern)] <= maxDist {
+			ends = append(ends, i)
+			for j := range d {
+				d[j] = j // restart not to match the same place again
+			}
+		}
+	}
+	return ends
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// fold width and katakana to hiragana, and drop spaces
+func foldKana(s string) string {
+	var b strings.Builder
+	for _, r := range ToHiragana(s) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
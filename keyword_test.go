@@ -0,0 +1,97 @@
+package recaius
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeywordSpotter(t *testing.T) {
+	k := NewKeywordSpotter(
+		Keyword{Name: "cancel", Phrases: []string{"解約"}, Readings: []string{"カイヤク"}},
+		Keyword{Name: "complaint", Phrases: []string{"苦情"}},
+	)
+	k.MinConfidence = 0.3
+
+	if es := k.Process(AsrResult{Type: "SOS"}); len(es) != 0 {
+		t.Fatal("unexpected events:", es)
+	}
+	es := k.Process(AsrResult{Type: "TMP_RESULT", OneBest: AsrOneBest{Type: "TMP_RESULT", Str: "契約を解約"}})
+	if len(es) != 1 || es[0].Keyword != "cancel" || es[0].Final {
+		t.Fatal("not spotted in partial:", es)
+	}
+	// the same keyword in the final result must not fire again
+	es = k.Process(AsrResult{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{
+		{Str: "契約を解約したい", Confidence: 0.8},
+	}}})
+	if len(es) != 0 {
+		t.Fatal("fired twice:", es)
+	}
+
+	// next utterance: reading match in an alternative
+	k.Process(AsrResult{Type: "SOS"})
+	es = k.Process(AsrResult{Type: "RESULT", Offset: 10 * time.Second, NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{
+		{Str: "会社を開く", Confidence: 0.6, Words: []AsrNBestWord{
+			{Str: "会社", Yomi: "カイシャ", Begin: 0, End: 500},
+			{Str: "を", Yomi: "ヲ", Begin: 500, End: 600},
+			{Str: "開く", Yomi: "ヒラク", Begin: 600, End: 1000},
+		}},
+		{Str: "回訳したい", Confidence: 0.4, Words: []AsrNBestWord{
+			{Str: "回訳", Yomi: "カイヤク", Begin: 200, End: 500, Offset: 10 * time.Second},
+			{Str: "したい", Yomi: "シタイ", Begin: 500, End: 1000, Offset: 10 * time.Second},
+		}},
+		{Str: "苦情です", Confidence: 0.1},
+	}}})
+	if len(es) != 1 || es[0].Keyword != "cancel" || !es[0].Reading || !es[0].Final {
+		t.Fatal("not spotted by reading:", es)
+	}
+	if es[0].Time != 10200*time.Millisecond {
+		t.Fatal("unexpected time:", es[0].Time)
+	}
+}
+
+func TestApproxMatches(t *testing.T) {
+	if ends := approxMatches([]rune("けいやくをかいやくする"), []rune("かいやく"), 0); len(ends) != 1 || ends[0] != 8 {
+		t.Fatal("unexpected exact matches:", ends)
+	}
+	if ends := approxMatches([]rune("かいあくする"), []rune("かいやく"), 1); len(ends) != 1 {
+		t.Fatal("unexpected fuzzy matches:", ends)
+	}
+}
+
+func TestKeywordSpotterConfidence(t *testing.T) {
+	k := NewKeywordSpotter(Keyword{Name: "cancel", Phrases: []string{"解約"}})
+	k.MinConfidence = 0.5
+
+	// a keyword in a low confidence best result does not fire
+	es := k.Process(AsrResult{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{
+		{Str: "解約したい", Confidence: 0.2},
+		{Str: "改訳したい", Confidence: 0.1},
+	}}})
+	if len(es) != 0 {
+		t.Fatal("low confidence result fired:", es)
+	}
+
+	// phrase matches take the time of their word
+	es = k.Process(AsrResult{Type: "RESULT", Offset: 5 * time.Second, NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{
+		{Str: "今日 解約 したい", Confidence: 0.9, Words: []AsrNBestWord{
+			{Str: "今日", Begin: 0, End: 300, Offset: 5 * time.Second},
+			{Str: "解約", Begin: 400, End: 800, Offset: 5 * time.Second},
+			{Str: "したい", Begin: 800, End: 1200, Offset: 5 * time.Second},
+		}},
+	}}})
+	if len(es) != 1 || es[0].Time != 5400*time.Millisecond {
+		t.Fatal("unexpected event:", es)
+	}
+}
+
+func TestPhraseWord(t *testing.T) {
+	words := []AsrNBestWord{{Str: "解約"}, {Str: "と"}, {Str: "カイ"}, {Str: "やく"}}
+	m := keywordMatch{phrase: "かいやく", count: 1}
+	if i := phraseWord(words, m); i != 2 {
+		t.Fatal("unexpected word:", i)
+	}
+	m = keywordMatch{phrase: "解約", count: 2}
+	if i := phraseWord(words, m); i != -1 {
+		t.Fatal("unexpected word:", i)
+	}
+}
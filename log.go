@@ -0,0 +1,53 @@
+package recaius
+
+// Logger receives structured events of the client, with args as
+// alternating keys and values. *slog.Logger satisfies it:
+//
+//	auth.Logger = slog.Default()
+//
+// Events are logged at Debug for each request (sends and polls), Info for
+// connections and tokens, and Warn for failures and retries.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// nopLogger is the default, which logs nothing
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+// values of these keys are never passed to a Logger
+var secretLogKeys = map[string]bool{
+	"token":    true,
+	"password": true,
+	"X-Token":  true,
+}
+
+const redacted = "REDACTED"
+
+// redactLogger redacts values of secretLogKeys, in case an event has one
+type redactLogger struct {
+	l Logger
+}
+
+func (r redactLogger) Debug(msg string, args ...interface{}) { r.l.Debug(msg, redactArgs(args)...) }
+func (r redactLogger) Info(msg string, args ...interface{})  { r.l.Info(msg, redactArgs(args)...) }
+func (r redactLogger) Warn(msg string, args ...interface{})  { r.l.Warn(msg, redactArgs(args)...) }
+func (r redactLogger) Error(msg string, args ...interface{}) { r.l.Error(msg, redactArgs(args)...) }
+
+func redactArgs(args []interface{}) []interface{} {
+	out := make([]interface{}, len(args))
+	copy(out, args)
+	for i := 0; i+1 < len(out); i += 2 {
+		if key, ok := out[i].(string); ok && secretLogKeys[key] {
+			out[i+1] = redacted
+		}
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package recaius
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+// testLogger records events as "level msg key=value ..."
+type testLogger struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *testLogger) log(level string, msg string, args []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := level + " " + msg
+	for i := 0; i+1 < len(args); i += 2 {
+		e += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	l.events = append(l.events, e)
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.log("INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log("WARN", msg, args) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.log("ERROR", msg, args) }
+
+func (l *testLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.events, "\n")
+}
+
+func TestRedactLogger(t *testing.T) {
+	l := &testLogger{}
+	redactLogger{l}.Info("event", "token", "t0", "uuid", "u", "password")
+	if got := l.String(); got != "INFO event token=REDACTED uuid=u" {
+		t.Fatal("unexpected event:", got)
+	}
+	nopLogger{}.Error("nothing")
+}
+
+func TestLoggerEvents(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("こんにちは")
+	l := &testLogger{}
+	auth := &Auth{
+		SpeechRecogJa: &ServiceInfo{ServiceId: "id", Password: "secret"},
+		BaseURL:       srv.URL,
+		Logger:        l,
+	}
+	if err := auth.Login(); err != nil {
+		t.Fatal("login failed:", err)
+	}
+	asr := NewAsrWithConfig(auth, &AsrConfig{ModelID: 1, PollingInterval: 10})
+	if _, err := recognizeFake(asr, make([]byte, 32000)); err != nil {
+		t.Fatal("recognize failed:", err)
+	}
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointCreate, Status: 500, Count: 1})
+	recognizeFake(asr, make([]byte, 32000))
+
+	log := l.String()
+	var uuid string
+	for _, req := range srv.Requests() {
+		if req.Endpoint == recaiustest.EndpointSend {
+			uuid = req.Voice
+			break
+		}
+	}
+	for _, want := range []string{
+		"INFO logged in services=[speech_recog_jaJP]",
+		"INFO connection created uuid=" + uuid,
+		"DEBUG sent uuid=" + uuid + " voice_id=1 size=32000",
+		"DEBUG flushed uuid=" + uuid + " voice_id=2",
+		"DEBUG polled uuid=" + uuid + " results=",
+		"INFO connection deleted uuid=" + uuid,
+		"WARN connection create failed err=",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("no event %q in\n%s", want, log)
+		}
+	}
+	for _, secret := range []string{"secret", "token-"} {
+		if strings.Contains(log, secret) {
+			t.Errorf("%q is logged:\n%s", secret, log)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package recaius
+
+import (
+	"expvar"
+	"time"
+)
+
+// Endpoints of the API, as reported to Metrics.
+const (
+	EndpointLogin   = "login"
+	EndpointExtend  = "extend"
+	EndpointLogout  = "logout"
+	EndpointCreate  = "create"
+	EndpointSend    = "send"
+	EndpointFlush   = "flush"
+	EndpointResults = "results"
+	EndpointDelete  = "delete"
+)
+
+// Classes of errors, as reported to Metrics.
+const (
+	ErrorClassNetwork = "network" // no response
+	ErrorClassAuth    = "auth"    // 401 or 403
+	ErrorClassClient  = "client"  // other 4xx
+	ErrorClassServer  = "server"  // 5xx
+	ErrorClassDecode  = "decode"  // unexpected response body
+)
+
+// Metrics receives measurements of the client. Methods are called
+// concurrently. ExpvarMetrics is an implementation.
+type Metrics interface {
+	PoolWait(d time.Duration)                 // waiting for a connection within MaxConnection
+	Request(endpoint string, d time.Duration) // latency of an HTTP call until its response header
+	Sent(bytes int, audio time.Duration)      // audio sent
+	FlushToFinal(d time.Duration)             // from a flush until its results are finished by NO_DATA
+	PollsPerUtterance(n int)                  // polls of results until a RESULT
+	Error(class string)                       // a failed call
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) PoolWait(d time.Duration)                 {}
+func (nopMetrics) Request(endpoint string, d time.Duration) {}
+func (nopMetrics) Sent(bytes int, audio time.Duration)      {}
+func (nopMetrics) FlushToFinal(d time.Duration)             {}
+func (nopMetrics) PollsPerUtterance(n int)                  {}
+func (nopMetrics) Error(class string)                       {}
+
+// class of an error response
+func statusClass(status int) string {
+	switch {
+	case status == 401 || status == 403:
+		return ErrorClassAuth
+	case status >= 500:
+		return ErrorClassServer
+	}
+	return ErrorClassClient
+}
+
+// ExpvarMetrics keeps Metrics in an expvar.Map. Durations are summed in
+// seconds with their counts, so that averages can be taken:
+//
+//	m := recaius.NewExpvarMetrics()
+//	expvar.Publish("recaius", m) // served at /debug/vars
+//	auth.Metrics = m
+type ExpvarMetrics struct {
+	*expvar.Map
+}
+
+func NewExpvarMetrics() *ExpvarMetrics {
+	m := &ExpvarMetrics{new(expvar.Map).Init()}
+	for _, name := range []string{"request_count", "request_seconds", "errors"} {
+		m.Set(name, new(expvar.Map).Init())
+	}
+	return m
+}
+
+func (m *ExpvarMetrics) addDuration(name string, d time.Duration) {
+	m.Add(name+"_count", 1)
+	m.AddFloat(name+"_seconds", d.Seconds())
+}
+
+func (m *ExpvarMetrics) submap(name string) *expvar.Map {
+	return m.Get(name).(*expvar.Map)
+}
+
+func (m *ExpvarMetrics) PoolWait(d time.Duration) {
+	m.addDuration("pool_wait", d)
+}
+
+func (m *ExpvarMetrics) Request(endpoint string, d time.Duration) {
+	m.submap("request_count").Add(endpoint, 1)
+	m.submap("request_seconds").AddFloat(endpoint, d.Seconds())
+}
+
+func (m *ExpvarMetrics) Sent(bytes int, audio time.Duration) {
+	m.Add("sent_bytes", int64(bytes))
+	m.AddFloat("sent_audio_seconds", audio.Seconds())
+}
+
+func (m *ExpvarMetrics) FlushToFinal(d time.Duration) {
+	m.addDuration("flush_to_final", d)
+}
+
+func (m *ExpvarMetrics) PollsPerUtterance(n int) {
+	m.Add("utterances", 1)
+	m.Add("utterance_polls", int64(n))
+}
+
+func (m *ExpvarMetrics) Error(class string) {
+	m.submap("errors").Add(class, 1)
+}
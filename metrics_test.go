@@ -0,0 +1,73 @@
+package recaius
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hi6tanaka/recaius/recaiustest"
+)
+
+func TestExpvarMetrics(t *testing.T) {
+	srv := recaiustest.NewServer()
+	defer srv.Close()
+	srv.DefaultTranscript("こんにちは")
+	m := NewExpvarMetrics()
+	auth := &Auth{
+		SpeechRecogJa: &ServiceInfo{ServiceId: "id", Password: "pass"},
+		BaseURL:       srv.URL,
+		Metrics:       m,
+	}
+	if err := auth.Login(); err != nil {
+		t.Fatal("login failed:", err)
+	}
+	asr := NewAsrWithConfig(auth, &AsrConfig{ModelID: 1, PollingInterval: 10})
+	if _, err := recognizeFake(asr, make([]byte, 64000)); err != nil {
+		t.Fatal("recognize failed:", err)
+	}
+	srv.Inject(recaiustest.Fault{Endpoint: recaiustest.EndpointCreate, Status: 500, Count: 1})
+	recognizeFake(asr, nil)
+	srv.ExpireTokens()
+	recognizeFake(asr, nil)
+
+	var got struct {
+		PoolWaitCount     int64              `json:"pool_wait_count"`
+		RequestCount      map[string]int64   `json:"request_count"`
+		RequestSeconds    map[string]float64 `json:"request_seconds"`
+		SentBytes         int64              `json:"sent_bytes"`
+		SentAudioSeconds  float64            `json:"sent_audio_seconds"`
+		FlushToFinalCount int64              `json:"flush_to_final_count"`
+		Utterances        int64              `json:"utterances"`
+		UtterancePolls    int64              `json:"utterance_polls"`
+		Errors            map[string]int64   `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(m.String()), &got); err != nil {
+		t.Fatal("invalid expvar:", err, m.String())
+	}
+	if got.PoolWaitCount != 3 {
+		t.Error("unexpected pool waits:", got.PoolWaitCount)
+	}
+	if got.RequestCount[EndpointLogin] != 1 || got.RequestCount[EndpointCreate] != 3 || got.RequestCount[EndpointSend] != 2 ||
+		got.RequestCount[EndpointFlush] != 1 || got.RequestCount[EndpointDelete] != 1 {
+		t.Error("unexpected requests:", got.RequestCount)
+	}
+	if got.RequestSeconds[EndpointSend] <= 0 {
+		t.Error("latency is not measured:", got.RequestSeconds)
+	}
+	if got.SentBytes != 64000 || got.SentAudioSeconds != 2 {
+		t.Error("unexpected audio sent:", got.SentBytes, got.SentAudioSeconds)
+	}
+	if got.FlushToFinalCount != 1 || got.Utterances != 1 || got.UtterancePolls != 1 {
+		t.Error("unexpected results:", got.FlushToFinalCount, got.Utterances, got.UtterancePolls)
+	}
+	if got.Errors[ErrorClassServer] != 1 || got.Errors[ErrorClassAuth] != 1 || len(got.Errors) != 2 {
+		t.Error("unexpected errors:", got.Errors)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{401: ErrorClassAuth, 403: ErrorClassAuth, 404: ErrorClassClient, 503: ErrorClassServer} {
+		if got := statusClass(status); got != want {
+			t.Error(status, "unexpected class:", got)
+		}
+	}
+}
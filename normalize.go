@@ -0,0 +1,269 @@
+package recaius
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AsrResultProcessor modifies results before sessions return them.
+// Processors in AsrConfig.Processors are applied in order, to partial and
+// final results alike.
+type AsrResultProcessor interface {
+	Process(r *AsrResult)
+}
+
+// Normalizer is an AsrResultProcessor to clean up Japanese text.
+type Normalizer struct {
+	Width       bool          // full-width alphanumerics to half-width, half-width katakana to full-width
+	Numerals    bool          // kanji numerals to digits
+	Punctuation bool          // insert 、 and 。 at pauses between words. needs nbest results
+	Space       bool          // remove spaces except between latin words
+	PauseComma  time.Duration // default 300ms
+	PausePeriod time.Duration // default 800ms
+}
+
+// NewNormalizer returns a Normalizer with all features enabled.
+func NewNormalizer() *Normalizer {
+	return &Normalizer{Width: true, Numerals: true, Punctuation: true, Space: true}
+}
+
+func (n *Normalizer) pauseComma() time.Duration {
+	if n.PauseComma <= 0 {
+		return 300 * time.Millisecond
+	}
+	return n.PauseComma
+}
+
+func (n *Normalizer) pausePeriod() time.Duration {
+	if n.PausePeriod <= 0 {
+		return 800 * time.Millisecond
+	}
+	return n.PausePeriod
+}
+
+func (n *Normalizer) Process(r *AsrResult) {
+	r.OneBest.Str = n.Normalize(r.OneBest.Str)
+	r.NBest.ResultTemp = n.Normalize(r.NBest.ResultTemp)
+	for i := range r.NBest.Result {
+		e := &r.NBest.Result[i]
+		if n.Punctuation && len(e.Words) > 0 {
+			n.punctuate(e)
+		}
+		e.Str = n.Normalize(e.Str)
+		for j := range e.Words {
+			e.Words[j].Str = n.Normalize(e.Words[j].Str)
+		}
+	}
+}
+
+// Normalize applies text-only steps (all but Punctuation) to s.
+func (n *Normalizer) Normalize(s string) string {
+	if n.Width {
+		s = NormalizeWidth(s)
+	}
+	if n.Numerals {
+		s = KanjiToDigits(s)
+	}
+	if n.Space {
+		s = cleanSpaces(s)
+	}
+	return s
+}
+
+// append punctuation to words followed by a pause, and rebuild the text
+func (n *Normalizer) punctuate(e *AsrNBestElement) {
+	words := e.Words
+	var text string
+	for i := range words {
+		if i+1 < len(words) {
+			pause := words[i+1].BeginTime() - words[i].EndTime()
+			if pause >= n.pausePeriod() {
+				addPunct(&words[i], "。")
+			} else if pause >= n.pauseComma() {
+				addPunct(&words[i], "、")
+			}
+		} else {
+			addPunct(&words[i], "。")
+		}
+		text = joinWord(text, words[i].Str)
+	}
+	e.Str = text
+}
+
+func addPunct(w *AsrNBestWord, p string) {
+	r, _ := utf8.DecodeLastRuneInString(w.Str)
+	if w.Str == "" || unicode.IsPunct(r) {
+		return
+	}
+	w.Str += p
+}
+
+// half-width katakana from U+FF66 to U+FF9D
+var halfKana = []rune("ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン")
+
+// NormalizeWidth converts full-width alphanumerics, symbols and space to
+// half-width, and half-width katakana to full-width like NFKC.
+func NormalizeWidth(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			r = r - 0xFF01 + 0x21
+		case r == 0x3000:
+			r = ' '
+		case r >= 0xFF66 && r <= 0xFF9D:
+			r = halfKana[r-0xFF66]
+			if i+1 < len(rs) {
+				switch rs[i+1] {
+				case 0xFF9E: // dakuten
+					if r == 'ウ' {
+						r = 'ヴ'
+						i++
+					} else if strings.ContainsRune("カキクケコサシスセソタチツテトハヒフヘホ", r) {
+						r++
+						i++
+					}
+				case 0xFF9F: // handakuten
+					if strings.ContainsRune("ハヒフヘホ", r) {
+						r += 2
+						i++
+					}
+				}
+			}
+		case r == 0xFF61:
+			r = '。'
+		case r == 0xFF64:
+			r = '、'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var kanjiDigits = map[rune]int64{
+	'〇': 0, '零': 0, '一': 1, '二': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+var kanjiUnits = map[rune]int64{'十': 10, '百': 100, '千': 1000}
+
+var kanjiBigUnits = map[rune]int64{'万': 1e4, '億': 1e8, '兆': 1e12}
+
+// counters which make a single kanji numeral a number, e.g. 三月, 五円
+const kanjiCounters = "年月日時分秒円人個回本枚件歳階番号台冊匹頭杯度割位%％"
+
+func isKanjiNumeral(r rune) bool {
+	_, d := kanjiDigits[r]
+	_, u := kanjiUnits[r]
+	_, b := kanjiBigUnits[r]
+	return d || u || b
+}
+
+// KanjiToDigits converts kanji numerals to arabic digits, e.g. 二〇二四年
+// to 2024年, 三千五百円 to 3500円. Digit sequences joined by の like phone
+// numbers are joined by hyphens. A single numeral is converted only before
+// a counter, so that words like 一緒 are kept.
+func KanjiToDigits(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	lastDigits := false // the last output was a positional digit sequence
+	for i := 0; i < len(rs); {
+		if !isKanjiNumeral(rs[i]) {
+			if rs[i] == 'の' && lastDigits && i+1 < len(rs) {
+				if _, ok := kanjiDigits[rs[i+1]]; ok {
+					if j := numeralEnd(rs, i+1); isPositional(rs[i+1 : j]) {
+						b.WriteRune('-')
+						i++
+						continue
+					}
+				}
+			}
+			b.WriteRune(rs[i])
+			lastDigits = false
+			i++
+			continue
+		}
+		j := numeralEnd(rs, i)
+		run := rs[i:j]
+		_, big := kanjiBigUnits[run[0]]
+		single := len(run) == 1 && (j >= len(rs) || !strings.ContainsRune(kanjiCounters, rs[j]))
+		if big || single {
+			b.WriteString(string(run))
+			lastDigits = false
+		} else if isPositional(run) {
+			for _, r := range run {
+				b.WriteByte(byte('0' + kanjiDigits[r]))
+			}
+			lastDigits = true
+		} else {
+			b.WriteString(strconv.FormatInt(parseKanjiNumber(run), 10))
+			lastDigits = false
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func numeralEnd(rs []rune, i int) int {
+	for i < len(rs) && isKanjiNumeral(rs[i]) {
+		i++
+	}
+	return i
+}
+
+// digits only, e.g. 二〇二四
+func isPositional(run []rune) bool {
+	for _, r := range run {
+		if _, ok := kanjiDigits[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parseKanjiNumber(run []rune) int64 {
+	var total, section int64
+	digit := int64(-1)
+	for _, r := range run {
+		if d, ok := kanjiDigits[r]; ok {
+			if digit < 0 {
+				digit = 0
+			}
+			digit = digit*10 + d
+		} else if u, ok := kanjiUnits[r]; ok {
+			if digit < 0 {
+				digit = 1
+			}
+			section += digit * u
+			digit = -1
+		} else if u, ok := kanjiBigUnits[r]; ok {
+			if digit > 0 {
+				section += digit
+			}
+			if section == 0 {
+				section = 1
+			}
+			total += section * u
+			section, digit = 0, -1
+		}
+	}
+	if digit > 0 {
+		section += digit
+	}
+	return total + section
+}
+
+// trim, and remove spaces except a single one between latin words
+func cleanSpaces(s string) string {
+	fields := strings.Fields(s)
+	var text string
+	for _, f := range fields {
+		text = joinWord(text, f)
+	}
+	return text
+}
@@ -0,0 +1,43 @@
+package recaius
+
+import "testing"
+
+func TestKanjiToDigits(t *testing.T) {
+	for in, want := range map[string]string{
+		"二〇二四年三月十五日":        "2024年3月15日",
+		"三千五百円です":           "3500円です",
+		"一億二千三百四十五万六千七百八十九": "123456789",
+		"〇三の一二三四の五六七八":      "03-1234-5678",
+		"一緒に十分待った":          "一緒に10分待った",
+		"万一の時は二十人":          "万一の時は20人",
+	} {
+		if got := KanjiToDigits(in); got != want {
+			t.Errorf("KanjiToDigits(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizer(t *testing.T) {
+	n := NewNormalizer()
+	if s := n.Normalize(" ＡＢＣ　ﾃﾞｰﾀ を 百個 "); s != "ABCデータを100個" {
+		t.Fatal("unexpected text:", s)
+	}
+
+	r := AsrResult{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{{
+		Str: "はい そうです 三時に 伺います",
+		Words: []AsrNBestWord{
+			{Str: "はい", Begin: 0, End: 300},
+			{Str: "そうです", Begin: 1200, End: 1800},
+			{Str: "三時に", Begin: 2200, End: 2700},
+			{Str: "伺います", Begin: 2750, End: 3300},
+		},
+	}}}}
+	n.Process(&r)
+	e := r.NBest.Result[0]
+	if e.Str != "はい。そうです、3時に伺います。" {
+		t.Fatal("unexpected punctuation:", e.Str)
+	}
+	if e.Words[2].Str != "3時に" {
+		t.Fatal("words are not normalized:", e.Words[2].Str)
+	}
+}
@@ -0,0 +1,163 @@
+package recaius
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// PaceStats summarizes chunks written by PacedWriter.
+type PaceStats struct {
+	Chunks       int
+	Bytes        int64
+	Audio        time.Duration // length of the audio written
+	TotalDelay   time.Duration // how late chunks were released against the schedule
+	MaxDelay     time.Duration
+	TotalLatency time.Duration // time taken by the underlying Write
+	MaxLatency   time.Duration
+}
+
+func (s PaceStats) MeanDelay() time.Duration {
+	if s.Chunks == 0 {
+		return 0
+	}
+	return s.TotalDelay / time.Duration(s.Chunks)
+}
+
+func (s PaceStats) MeanLatency() time.Duration {
+	if s.Chunks == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Chunks)
+}
+
+var errNoChunkSize = errors.New("PacedWriter needs ChunkSize, or SampleRate and BytesPerSample")
+
+// PacedWriter releases audio to the underlying writer (e.g. AsrStreamSession)
+// at real-time speed, as if it was captured from a microphone.
+// A chunk is released when its last sample would have been recorded.
+type PacedWriter struct {
+	SampleRate     int     // Hz
+	BytesPerSample int     // including all channels
+	Speed          float64 // multiple of real-time speed. 0 is treated as 1
+	ChunkSize      int     // byte. if > 0, each Write is split into chunks of this size
+
+	w       io.Writer
+	start   time.Time
+	written int64
+	stats   PaceStats
+	now     func() time.Time    // replaced in tests
+	sleep   func(time.Duration) // replaced in tests
+}
+
+func NewPacedWriter(w io.Writer, sampleRate int, bytesPerSample int, speed float64) *PacedWriter {
+	return &PacedWriter{
+		SampleRate:     sampleRate,
+		BytesPerSample: bytesPerSample,
+		Speed:          speed,
+		w:              w,
+		now:            time.Now,
+		sleep:          time.Sleep,
+	}
+}
+
+func (p *PacedWriter) Write(b []byte) (int, error) {
+	n := 0
+	for len(b) > 0 {
+		size := len(b)
+		if p.ChunkSize > 0 && size > p.ChunkSize {
+			size = p.ChunkSize
+		}
+		m, err := p.writeChunk(b[:size])
+		n += m
+		if err != nil {
+			return n, err
+		}
+		b = b[size:]
+	}
+	return n, nil
+}
+
+// ReadFrom copies r to the underlying writer in chunks of ChunkSize
+// (or 100 millisecond if not set).
+func (p *PacedWriter) ReadFrom(r io.Reader) (int64, error) {
+	size := p.ChunkSize
+	if size <= 0 {
+		size = p.SampleRate * p.BytesPerSample / 10
+	}
+	if size <= 0 {
+		return 0, errNoChunkSize
+	}
+	var n int64
+	buf := make([]byte, size)
+	for {
+		m, err := io.ReadFull(r, buf)
+		if m > 0 {
+			w, werr := p.writeChunk(buf[:m])
+			n += int64(w)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// Stats returns the summary of chunks written so far.
+func (p *PacedWriter) Stats() PaceStats {
+	return p.stats
+}
+
+func (p *PacedWriter) writeChunk(b []byte) (int, error) {
+	if p.now == nil {
+		p.now, p.sleep = time.Now, time.Sleep
+	}
+	if p.start.IsZero() {
+		p.start = p.now()
+	}
+	due := p.start.Add(p.scale(p.duration(p.written + int64(len(b)))))
+	if d := due.Sub(p.now()); d > 0 {
+		p.sleep(d)
+	}
+	t := p.now()
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.record(n, t.Sub(due), p.now().Sub(t))
+	return n, err
+}
+
+func (p *PacedWriter) record(n int, delay time.Duration, latency time.Duration) {
+	s := &p.stats
+	s.Chunks++
+	s.Bytes += int64(n)
+	s.Audio = p.duration(p.written)
+	s.TotalDelay += delay
+	if delay > s.MaxDelay {
+		s.MaxDelay = delay
+	}
+	s.TotalLatency += latency
+	if latency > s.MaxLatency {
+		s.MaxLatency = latency
+	}
+}
+
+// audio duration of n bytes
+func (p *PacedWriter) duration(n int64) time.Duration {
+	bytesPerSec := int64(p.SampleRate * p.BytesPerSample)
+	if bytesPerSec <= 0 {
+		return 0
+	}
+	return time.Duration(n * int64(time.Second) / bytesPerSec)
+}
+
+func (p *PacedWriter) scale(d time.Duration) time.Duration {
+	if p.Speed <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) / p.Speed)
+}
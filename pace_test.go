@@ -0,0 +1,88 @@
+package recaius
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only by sleeps and writes
+type fakeClock struct {
+	t      time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.t = c.t.Add(d)
+}
+
+// slowWriter takes d for each write
+type slowWriter struct {
+	bytes.Buffer
+	clock *fakeClock
+	d     time.Duration
+	sizes []int
+}
+
+func (w *slowWriter) Write(b []byte) (int, error) {
+	w.clock.t = w.clock.t.Add(w.d)
+	w.sizes = append(w.sizes, len(b))
+	return w.Buffer.Write(b)
+}
+
+func newFakePacedWriter(speed float64, latency time.Duration) (*PacedWriter, *slowWriter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	w := &slowWriter{clock: clock, d: latency}
+	p := NewPacedWriter(w, 16000, 2, speed)
+	p.now, p.sleep = clock.now, clock.sleep
+	return p, w, clock
+}
+
+func TestPacedWriter(t *testing.T) {
+	p, w, clock := newFakePacedWriter(2, 0)
+	p.ChunkSize = 3200 // 100ms
+	if n, err := p.Write(make([]byte, 6400)); n != 6400 || err != nil {
+		t.Fatal("unexpected write:", n, err)
+	}
+	// each chunk waits until its end at double speed
+	if len(clock.sleeps) != 2 || clock.sleeps[0] != 50*time.Millisecond || clock.sleeps[1] != 50*time.Millisecond {
+		t.Fatal("unexpected sleeps:", clock.sleeps)
+	}
+	if len(w.sizes) != 2 || w.sizes[0] != 3200 {
+		t.Fatal("unexpected chunks:", w.sizes)
+	}
+}
+
+func TestPacedWriterStats(t *testing.T) {
+	p, _, clock := newFakePacedWriter(1, 150*time.Millisecond)
+	p.ChunkSize = 3200
+	p.Write(make([]byte, 9600))
+	s := p.Stats()
+	if s.Chunks != 3 || s.Bytes != 9600 || s.Audio != 300*time.Millisecond {
+		t.Fatal("unexpected stats:", s)
+	}
+	// writes of 150ms make chunks of 100ms late by 0, 50ms and 100ms
+	if s.MaxLatency != 150*time.Millisecond || s.MaxDelay != 100*time.Millisecond || s.MeanDelay() != 50*time.Millisecond {
+		t.Fatal("unexpected delays:", s, clock.sleeps)
+	}
+}
+
+func TestPacedWriterReadFrom(t *testing.T) {
+	p, w, _ := newFakePacedWriter(1, 0)
+	n, err := p.ReadFrom(bytes.NewReader(make([]byte, 8000)))
+	if n != 8000 || err != nil {
+		t.Fatal("unexpected copy:", n, err)
+	}
+	// chunks of 100ms by default, and the rest
+	if len(w.sizes) != 3 || w.sizes[0] != 3200 || w.sizes[2] != 1600 {
+		t.Fatal("unexpected chunks:", w.sizes)
+	}
+
+	p = NewPacedWriter(w, 0, 0, 1)
+	if _, err := p.ReadFrom(bytes.NewReader(make([]byte, 10))); err != errNoChunkSize {
+		t.Fatal("unknown chunk size is accepted:", err)
+	}
+}
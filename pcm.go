@@ -0,0 +1,114 @@
+package recaius
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// BleepMode is how AudioRedactor replaces audio.
+type BleepMode int
+
+const (
+	BleepSilence BleepMode = iota
+	BleepTone
+)
+
+// AudioRedactor replaces time ranges of 16bit linear PCM with silence or a
+// tone. Edges are faded to avoid clicks. It pairs with Redactor.TimeRanges.
+type AudioRedactor struct {
+	Mode      BleepMode
+	ToneFreq  float64       // Hz. default 1000
+	ToneLevel float64       // ratio to full scale. default 0.3
+	Fade      time.Duration // default 5ms
+}
+
+func (a *AudioRedactor) toneFreq() float64 {
+	if a.ToneFreq <= 0 {
+		return 1000
+	}
+	return a.ToneFreq
+}
+
+func (a *AudioRedactor) toneLevel() float64 {
+	if a.ToneLevel <= 0 {
+		return 0.3
+	}
+	return a.ToneLevel
+}
+
+func (a *AudioRedactor) fade() time.Duration {
+	if a.Fade <= 0 {
+		return 5 * time.Millisecond
+	}
+	return a.Fade
+}
+
+// RedactWav redacts the data of a wav file in place.
+func (a *AudioRedactor) RedactWav(w *Wav, ranges []Interval) error {
+	if w.Tag != 0 && w.Tag != wavFormatPCM {
+		return fmt.Errorf("wav format %#x is not supported", w.Tag)
+	}
+	return a.Redact(w.Data, w.Format, ranges)
+}
+
+// Redact redacts 16bit little endian linear PCM in place.
+func (a *AudioRedactor) Redact(data []byte, f AudioFormat, ranges []Interval) error {
+	if f.BitsPerSample != 16 {
+		return fmt.Errorf("%d bit audio is not supported", f.BitsPerSample)
+	}
+	channels := f.Channels
+	if channels <= 0 {
+		channels = 1
+	}
+	frames := len(data) / 2 / channels
+	rate := float64(f.SampleRate)
+	fade := a.fade().Seconds()
+
+	for _, r := range a.merge(ranges) {
+		start, end := r.Start.Seconds(), r.End.Seconds()
+		from := int(math.Max(0, (start-fade)*rate))
+		to := int(math.Min(float64(frames), math.Ceil((end+fade)*rate)))
+		for i := from; i < to; i++ {
+			t := float64(i) / rate
+			// gain of the original audio
+			g := 0.0
+			if t < start {
+				g = (start - t) / fade
+			} else if t > end {
+				g = (t - end) / fade
+			}
+			g = math.Min(g, 1)
+			var tone float64
+			if a.Mode == BleepTone {
+				tone = (1 - g) * a.toneLevel() * 32767 * math.Sin(2*math.Pi*a.toneFreq()*t)
+			}
+			for c := 0; c < channels; c++ {
+				p := (i*channels + c) * 2
+				x := float64(int16(binary.LittleEndian.Uint16(data[p:])))
+				y := math.Max(-32768, math.Min(32767, x*g+tone))
+				binary.LittleEndian.PutUint16(data[p:], uint16(int16(math.Round(y))))
+			}
+		}
+	}
+	return nil
+}
+
+// sort ranges, and merge ones whose fades overlap
+func (a *AudioRedactor) merge(ranges []Interval) []Interval {
+	rs := append([]Interval(nil), ranges...)
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Start < rs[j].Start })
+	var merged []Interval
+	for _, r := range rs {
+		if n := len(merged); n > 0 && r.Start <= merged[n-1].End+2*a.fade() {
+			if r.End > merged[n-1].End {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,49 @@
+package recaius
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestAudioRedactor(t *testing.T) {
+	w := &Wav{Format: LinearPCM16k, Data: makeTone(time.Second, 3000)}
+	a := &AudioRedactor{}
+	if err := a.RedactWav(w, []Interval{{Start: 400 * time.Millisecond, End: 600 * time.Millisecond}}); err != nil {
+		t.Fatal("redact error:", err)
+	}
+	rms := func(from, to time.Duration) float64 {
+		r, _ := frameFeatures(w.Data[int(from*16000/time.Second)*2 : int(to*16000/time.Second)*2])
+		return r
+	}
+	if r := rms(400*time.Millisecond, 600*time.Millisecond); r != 0 {
+		t.Fatal("range is not silenced:", r)
+	}
+	if r := rms(100*time.Millisecond, 390*time.Millisecond); r < 2000 {
+		t.Fatal("outside of range is changed:", r)
+	}
+	// fading
+	if r := rms(397*time.Millisecond, 400*time.Millisecond); r == 0 || r > 2000 {
+		t.Fatal("not faded:", r)
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal("write error:", err)
+	}
+	parsed, err := ParseWav(buf.Bytes())
+	if err != nil {
+		t.Fatal("written wav is invalid:", err)
+	}
+	if parsed.Format != LinearPCM16k || !bytes.Equal(parsed.Data, w.Data) {
+		t.Fatal("written wav differs:", parsed.Format)
+	}
+
+	tone := &AudioRedactor{Mode: BleepTone}
+	if err := tone.Redact(w.Data, w.Format, []Interval{{Start: 400 * time.Millisecond, End: 600 * time.Millisecond}}); err != nil {
+		t.Fatal("redact error:", err)
+	}
+	if r := rms(450*time.Millisecond, 550*time.Millisecond); r < 5000 {
+		t.Fatal("tone is not inserted:", r)
+	}
+}
@@ -19,8 +19,7 @@ func (e ResponseError) Error() string {
 }
 
 // You must Close response if not nil
-func callApi(auth *Auth, method string, url string, body io.Reader, contentType string) (*http.Response, error) {
-	client := &http.Client{}
+func callApi(auth *Auth, endpoint string, method string, url string, body io.Reader, contentType string) (*http.Response, error) {
 	req, err := auth.MakeAuthorizedRequest(method, url, body)
 	if err != nil {
 		return nil, err
@@ -29,7 +28,7 @@ func callApi(auth *Auth, method string, url string, body io.Reader, contentType
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := auth.do(endpoint, req)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +36,7 @@ func callApi(auth *Auth, method string, url string, body io.Reader, contentType
 		defer resp.Body.Close()
 		var rt ResponseError
 		if err := json.NewDecoder(resp.Body).Decode(&rt); err != nil {
+			auth.metrics().Error(ErrorClassDecode)
 			return nil, err
 		}
 		return nil, rt
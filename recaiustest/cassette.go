@@ -0,0 +1,246 @@
+package recaiustest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Interaction is a recorded request and its response. Audio is not kept,
+// only its size.
+type Interaction struct {
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	VoiceID     int64           `json:"voice_id,omitempty"`
+	Request     json.RawMessage `json:"request,omitempty"` // JSON body, scrubbed
+	AudioSize   int             `json:"audio_size,omitempty"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Response    string          `json:"response"` // body, scrubbed
+}
+
+// Cassette is a recorded session, saved as JSON.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette saved by Recorder.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// scrubbed replaces values of secrets in recorded JSON
+const scrubbed = "REDACTED"
+
+var secretKeys = map[string]bool{"token": true, "password": true}
+
+// scrub returns JSON with secrets replaced, or nil if data is not JSON.
+func scrub(data []byte) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	out, err := json.Marshal(scrubValue(v))
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func scrubValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, x := range v {
+			if secretKeys[key] {
+				v[key] = scrubbed
+			} else {
+				v[key] = scrubValue(x)
+			}
+		}
+	case []interface{}:
+		for i, x := range v {
+			v[i] = scrubValue(x)
+		}
+	}
+	return v
+}
+
+// readRequest reads the body of req into an interaction, and returns req
+// with the body restored.
+func readRequest(req *http.Request) (*http.Request, Interaction, error) {
+	it := Interaction{Method: req.Method, Path: req.URL.Path}
+	if req.Body == nil {
+		return req, it, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, it, err
+	}
+	out := new(http.Request)
+	*out = *req
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		parsed := new(http.Request)
+		*parsed = *req
+		parsed.Body = ioutil.NopCloser(bytes.NewReader(body))
+		var audio []byte
+		it.VoiceID, _, audio, err = readVoice(parsed)
+		if err != nil {
+			return nil, it, err
+		}
+		it.AudioSize = len(audio)
+	} else if len(body) > 0 {
+		it.Request = scrub(body)
+		var v struct {
+			VoiceID int64 `json:"voice_id"`
+		}
+		json.Unmarshal(body, &v)
+		it.VoiceID = v.VoiceID
+	}
+	return out, it, nil
+}
+
+// Recorder is a http.RoundTripper which records interactions through
+// Transport to a cassette. Tokens and passwords are scrubbed.
+//
+//	rec := recaiustest.NewRecorder(nil)
+//	auth.HTTPClient = &http.Client{Transport: rec}
+//	...
+//	rec.Cassette().Save("testdata/session.json")
+type Recorder struct {
+	Transport http.RoundTripper // default http.DefaultTransport
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport}
+}
+
+func (r *Recorder) transport() http.RoundTripper {
+	if r.Transport == nil {
+		return http.DefaultTransport
+	}
+	return r.Transport
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, it, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	it.Status = resp.StatusCode
+	it.ContentType = resp.Header.Get("Content-Type")
+	if s := scrub(body); s != nil {
+		it.Response = string(s)
+	} else {
+		it.Response = string(body)
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, it)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Cassette returns interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Replayer is a http.RoundTripper which responds with a cassette, without
+// network. A request gets the first unused interaction with the same
+// method, path and voice_id; the host and tokens are ignored.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}
+}
+
+func (p *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, it, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, x := range p.interactions {
+		if p.used[i] || x.Method != it.Method || x.Path != it.Path || x.VoiceID != it.VoiceID {
+			continue
+		}
+		p.used[i] = true
+		header := http.Header{}
+		if x.ContentType != "" {
+			header.Set("Content-Type", x.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", x.Status, http.StatusText(x.Status)),
+			StatusCode:    x.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(x.Response)),
+			ContentLength: int64(len(x.Response)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("recaiustest: no interaction for %s %s voice_id=%d", it.Method, it.Path, it.VoiceID)
+}
+
+// Unused returns interactions not replayed yet, e.g. to check a test made
+// the same requests as the recording.
+func (p *Replayer) Unused() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var rest []Interaction
+	for i, x := range p.interactions {
+		if !p.used[i] {
+			rest = append(rest, x)
+		}
+	}
+	return rest
+}
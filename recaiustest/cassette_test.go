@@ -0,0 +1,42 @@
+package recaiustest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	got := string(scrub([]byte(`{"token":"t0","speech_recog_jaJP":{"service_id":"id","password":"p"},"expiry_sec":600}`)))
+	want := `{"expiry_sec":600,"speech_recog_jaJP":{"password":"REDACTED","service_id":"id"},"token":"REDACTED"}`
+	if got != want {
+		t.Fatal("unexpected scrub:", got)
+	}
+	if scrub([]byte("not json")) != nil {
+		t.Fatal("non JSON is scrubbed")
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	p := NewReplayer(&Cassette{Interactions: []Interaction{
+		{Method: "PUT", Path: "/asr/v2/voices/v/flush", VoiceID: 2, Status: 200, Response: `[["RESULT","a"]]`},
+		{Method: "GET", Path: "/asr/v2/voices/v/results", Status: 200, Response: `[]`},
+		{Method: "GET", Path: "/asr/v2/voices/v/results", Status: 200, Response: `[["NO_DATA",""]]`},
+	}})
+	c := &client{t: t, url: "http://recaius.invalid", http: &http.Client{Transport: p}}
+
+	if _, err := c.try("PUT", "/asr/v2/voices/v/flush", []byte(`{"voice_id":3}`), "application/json"); err == nil || !strings.Contains(err.Error(), "voice_id=3") {
+		t.Fatal("voice_id is not matched:", err)
+	}
+	var rs [][2]string
+	c.do("PUT", "/asr/v2/voices/v/flush", []byte(`{"voice_id":2}`), "application/json", &rs)
+	if len(rs) != 1 || rs[0][1] != "a" {
+		t.Fatal("unexpected response:", rs)
+	}
+	// repeated requests get interactions in order
+	c.do("GET", "/asr/v2/voices/v/results", nil, "", &rs)
+	c.do("GET", "/asr/v2/voices/v/results", nil, "", &rs)
+	if len(rs) != 1 || rs[0][0] != "NO_DATA" || len(p.Unused()) != 0 {
+		t.Fatal("unexpected response:", rs)
+	}
+}
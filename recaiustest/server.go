@@ -0,0 +1,370 @@
+// Package recaiustest provides a fake RECAIUS server for tests.
+//
+//	srv := recaiustest.NewServer()
+//	defer srv.Close()
+//	srv.DefaultTranscript("こんにちは")
+//	auth := &recaius.Auth{
+//		SpeechRecogJa: &recaius.ServiceInfo{ServiceId: "id", Password: "pass"},
+//		BaseURL:       srv.URL,
+//	}
+//
+// Recorder and Replayer record a session with a real server, and replay it
+// offline.
+//
+// It does not import recaius, so that tests of recaius can use it.
+package recaiustest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoints of the API, to inject faults into.
+const (
+	EndpointLogin   = "login"   // POST /auth/v2/tokens
+	EndpointExtend  = "extend"  // PUT /auth/v2/tokens
+	EndpointLogout  = "logout"  // DELETE /auth/v2/tokens
+	EndpointCreate  = "create"  // POST /asr/v2/voices
+	EndpointSend    = "send"    // PUT /asr/v2/voices/UUID
+	EndpointFlush   = "flush"   // PUT /asr/v2/voices/UUID/flush
+	EndpointResults = "results" // GET /asr/v2/voices/UUID/results
+	EndpointDelete  = "delete"  // DELETE /asr/v2/voices/UUID
+)
+
+// Result is a recognition result.
+type Result struct {
+	Type       string // SOS, TMP_RESULT, RESULT or NO_DATA
+	Text       string
+	Confidence float64 // of RESULT in nbest
+	Words      []Word  // of RESULT in nbest
+}
+
+// Word is a word of a nbest result. Begin and End are in milliseconds from
+// the start of the utterance: the chunk that returned its SOS.
+type Word struct {
+	Str        string  `json:"str"`
+	Yomi       string  `json:"yomi"`
+	Confidence float64 `json:"confidence"`
+	Begin      int64   `json:"begin"`
+	End        int64   `json:"end"`
+}
+
+// Utterance returns results of a sentence: SOS, a partial of the first half,
+// and the final result.
+func Utterance(text string, words ...Word) []Result {
+	rs := []rune(text)
+	return []Result{
+		{Type: "SOS"},
+		{Type: "TMP_RESULT", Text: string(rs[:len(rs)/2])},
+		{Type: "RESULT", Text: text, Confidence: 1, Words: words},
+	}
+}
+
+// Fault makes matching requests fail or slow.
+type Fault struct {
+	Endpoint    string        // one of Endpoint constants. "" matches all
+	Latency     time.Duration // delay before responding
+	Status      int           // respond with this status and an error body, e.g. 500 or 401
+	DropResults bool          // results in the response are lost. NO_DATA is kept so that sessions finish
+	Count       int           // times to apply. 0 for every request
+}
+
+// Request is a request the server received, to assert on.
+type Request struct {
+	Endpoint  string
+	Method    string
+	Path      string
+	Token     string   // X-Token
+	Voice     string   // UUID of the voice
+	VoiceID   int64    // voice_id of send and flush
+	Fields    []string // names of multipart fields of send in order
+	AudioSize int      // size of the voice field of send
+	Status    int      // of the response
+}
+
+// Server is a fake of the auth and ASR APIs. Any credentials are accepted,
+// but tokens must be valid, and voice_id must be in order.
+//
+// A voice behaves like the real service: the first audio of a flush cycle
+// returns SOS, sends return partials, and after a flush, following requests
+// return the final result and NO_DATA. The transcript of a cycle is chosen by
+// Transcribe, TranscribeChunks and DefaultTranscript.
+type Server struct {
+	URL string
+
+	ts          *httptest.Server
+	mu          sync.Mutex
+	script      []Result
+	rules       []rule
+	defaultText string
+	faults      []*Fault
+	tokens      map[string]time.Time
+	voices      map[string]*voice
+	requests    []Request
+	serial      int
+}
+
+func NewServer() *Server {
+	s := &Server{
+		tokens: map[string]time.Time{},
+		voices: map[string]*voice{},
+	}
+	s.ts = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.ts.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+// Script sets canned results returned for each flush, instead of
+// the transcript. A leading SOS is returned for the first audio of a flush
+// cycle, like the real service, and the rest after the flush.
+func (s *Server) Script(rs ...Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = append([]Result(nil), rs...)
+}
+
+// Inject adds a fault. The first matching fault applies to a request.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// Voices returns the number of voices not deleted.
+func (s *Server) Voices() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.voices)
+}
+
+// Requests returns requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ExpireTokens makes all tokens expired, so that requests with them fail
+// with 401 until login.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token := range s.tokens {
+		s.tokens[token] = time.Time{}
+	}
+}
+
+// take the first fault matching the endpoint
+func (s *Server) fault(endpoint string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if f.Endpoint != "" && f.Endpoint != endpoint {
+			continue
+		}
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// statusWriter remembers the status of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) serve(rw http.ResponseWriter, r *http.Request) {
+	endpoint, id := route(r)
+	req := &Request{
+		Endpoint: endpoint,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Token:    r.Header.Get("X-Token"),
+		Voice:    id,
+	}
+	w := &statusWriter{ResponseWriter: rw}
+	defer func() {
+		req.Status = w.status
+		if req.Status == 0 {
+			req.Status = http.StatusOK
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, *req)
+		s.mu.Unlock()
+	}()
+
+	if endpoint == "" {
+		writeError(w, http.StatusNotFound, "")
+		return
+	}
+	f := s.fault(endpoint)
+	if f != nil {
+		time.Sleep(f.Latency)
+		if f.Status != 0 {
+			writeError(w, f.Status, "")
+			return
+		}
+	}
+	if endpoint != EndpointLogin && !s.validToken(req.Token) {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	switch endpoint {
+	case EndpointLogin, EndpointExtend, EndpointLogout:
+		s.serveToken(w, r, endpoint)
+	case EndpointCreate:
+		s.serveCreate(w, r)
+	default:
+		s.serveVoice(w, r, req, f != nil && f.DropResults)
+	}
+}
+
+// route returns the endpoint of a request, and the UUID of the voice
+func route(r *http.Request) (string, string) {
+	path := r.URL.Path
+	if path == "/auth/v2/tokens" {
+		switch r.Method {
+		case "POST":
+			return EndpointLogin, ""
+		case "PUT":
+			return EndpointExtend, ""
+		case "DELETE":
+			return EndpointLogout, ""
+		}
+		return "", ""
+	}
+	if path == "/asr/v2/voices" && r.Method == "POST" {
+		return EndpointCreate, ""
+	}
+	if !strings.HasPrefix(path, "/asr/v2/voices/") {
+		return "", ""
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/asr/v2/voices/"), "/")
+	switch {
+	case len(parts) == 1 && r.Method == "PUT":
+		return EndpointSend, parts[0]
+	case len(parts) == 1 && r.Method == "DELETE":
+		return EndpointDelete, parts[0]
+	case len(parts) == 2 && parts[1] == "flush" && r.Method == "PUT":
+		return EndpointFlush, parts[0]
+	case len(parts) == 2 && parts[1] == "results" && r.Method == "GET":
+		return EndpointResults, parts[0]
+	}
+	return "", ""
+}
+
+func (s *Server) validToken(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expireAt, ok := s.tokens[token]
+	return ok && time.Now().Before(expireAt)
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request, endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch endpoint {
+	case EndpointLogin:
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var expiry int64
+		json.Unmarshal(body["expiry_sec"], &expiry)
+		if expiry <= 0 {
+			expiry = 3600
+		}
+		if !hasCredentials(body) {
+			writeError(w, http.StatusUnauthorized, "no service credentials")
+			return
+		}
+		s.serial++
+		token := fmt.Sprintf("token-%d", s.serial)
+		s.tokens[token] = time.Now().Add(time.Duration(expiry) * time.Second)
+		writeJSON(w, map[string]interface{}{"token": token, "expiry_sec": expiry})
+	case EndpointExtend:
+		token := r.Header.Get("X-Token")
+		s.tokens[token] = time.Now().Add(time.Hour)
+		writeJSON(w, map[string]interface{}{"token": token, "expiry_sec": 3600})
+	case EndpointLogout:
+		delete(s.tokens, r.Header.Get("X-Token"))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// login needs a service with an ID and a password
+func hasCredentials(body map[string]json.RawMessage) bool {
+	for key, value := range body {
+		if !strings.HasPrefix(key, "speech_recog_") {
+			continue
+		}
+		var info struct {
+			ServiceID string `json:"service_id"`
+			Password  string `json:"password"`
+		}
+		if json.Unmarshal(value, &info) == nil && info.ServiceID != "" && info.Password != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) serveCreate(w http.ResponseWriter, r *http.Request) {
+	var config struct {
+		AudioType  string `json:"audio_type"`
+		ResultType string `json:"result_type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serial++
+	id := fmt.Sprintf("00000000-0000-0000-0000-%012d", s.serial)
+	s.voices[id] = newVoice(config.AudioType, config.ResultType)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"uuid": id})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error body which recaius.ResponseError can decode
+func writeError(w http.ResponseWriter, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    status,
+		"message": message,
+	})
+}
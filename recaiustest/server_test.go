@@ -0,0 +1,113 @@
+package recaiustest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+type client struct {
+	t     *testing.T
+	url   string
+	token string
+	http  *http.Client // default http.DefaultClient
+}
+
+func (c *client) try(method string, path string, body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("X-Token", c.token)
+	req.Header.Set("Content-Type", contentType)
+	if c.http == nil {
+		return http.DefaultClient.Do(req)
+	}
+	return c.http.Do(req)
+}
+
+func (c *client) do(method string, path string, body []byte, contentType string, out interface{}) int {
+	resp, err := c.try(method, path, body, contentType)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (c *client) send(uuid string, voiceID int64, audio []byte) (int, [][2]string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("voice_id", strconv.FormatInt(voiceID, 10))
+	fw, _ := w.CreateFormField("voice")
+	fw.Write(audio)
+	w.Close()
+	var rs [][2]string
+	status := c.do("PUT", "/asr/v2/voices/"+uuid, body.Bytes(), w.FormDataContentType(), &rs)
+	return status, rs
+}
+
+func TestServerProtocol(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.TranscribeChunks(2, "あいうえ")
+	c := &client{t: t, url: s.URL}
+
+	if status := c.do("POST", "/asr/v2/voices", []byte(`{"model_id":1}`), "application/json", nil); status != 401 {
+		t.Fatal("request without token is accepted:", status)
+	}
+	if status := c.do("POST", "/auth/v2/tokens", []byte(`{"speech_recog_jaJP":{"service_id":"id"}}`), "application/json", nil); status != 401 {
+		t.Fatal("login without password is accepted:", status)
+	}
+	var token struct{ Token string }
+	c.do("POST", "/auth/v2/tokens", []byte(`{"speech_recog_jaJP":{"service_id":"id","password":"pass"}}`), "application/json", &token)
+	c.token = token.Token
+
+	var v struct{ UUID string }
+	c.do("POST", "/asr/v2/voices", []byte(`{"model_id":1}`), "application/json", &v)
+
+	if status, _ := c.send(v.UUID, 2, []byte{0, 0}); status != 400 {
+		t.Fatal("voice_id out of order is accepted:", status)
+	}
+	if _, rs := c.send(v.UUID, 1, []byte{0, 0}); len(rs) != 2 || rs[0][0] != "SOS" || rs[1] != [2]string{"TMP_RESULT", "あい"} {
+		t.Fatal("first audio does not make SOS and a partial:", rs)
+	}
+	if _, rs := c.send(v.UUID, 2, []byte{0, 0}); len(rs) != 0 {
+		t.Fatal("unexpected results before flush:", rs)
+	}
+	if status := c.do("PUT", "/asr/v2/voices/"+v.UUID+"/flush", []byte(`{"voice_id":2}`), "application/json", nil); status != 400 {
+		t.Fatal("flush with a used voice_id is accepted:", status)
+	}
+	c.do("PUT", "/asr/v2/voices/"+v.UUID+"/flush", []byte(`{"voice_id":3}`), "application/json", nil)
+	var rs [][2]string
+	c.do("GET", "/asr/v2/voices/"+v.UUID+"/results", nil, "", &rs)
+	want := [][2]string{{"RESULT", "あいうえ"}, {"NO_DATA", ""}}
+	if len(rs) != len(want) {
+		t.Fatal("unexpected results:", rs)
+	}
+	for i := range want {
+		if rs[i] != want[i] {
+			t.Fatal("unexpected results:", rs)
+		}
+	}
+
+	s.ExpireTokens()
+	if status := c.do("GET", "/asr/v2/voices/"+v.UUID+"/results", nil, "", nil); status != 401 {
+		t.Fatal("expired token is accepted:", status)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	words := splitWords("ab cd ef", 600)
+	if len(words) != 3 || words[1].Str != "cd" || words[1].Begin != 200 || words[1].End != 400 {
+		t.Fatal("unexpected words:", words)
+	}
+}
@@ -0,0 +1,367 @@
+package recaiustest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fingerprint returns the fingerprint of audio for Transcribe.
+func Fingerprint(audio []byte) string {
+	sum := sha256.Sum256(audio)
+	return hex.EncodeToString(sum[:])
+}
+
+type rule struct {
+	fingerprint string
+	chunks      int
+	text        string
+}
+
+// Transcribe makes a flush cycle recognized as text if all its audio has
+// the fingerprint.
+func (s *Server) Transcribe(fingerprint string, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, rule{fingerprint: fingerprint, text: text})
+}
+
+// TranscribeChunks makes a flush cycle of n chunks recognized as text.
+// Fingerprints take precedence. Lines of text are utterances spread evenly
+// over the chunks: the chunk starting an utterance returns the RESULT of
+// the previous one and SOS.
+func (s *Server) TranscribeChunks(n int, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, rule{chunks: n, text: text})
+}
+
+// DefaultTranscript is the text of flush cycles matching no rule.
+// An empty text makes no final result.
+func (s *Server) DefaultTranscript(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultText = text
+}
+
+// transcript of a cycle, with chunks of its TranscribeChunks rule or 0
+func (s *Server) transcript(c *cycle) (string, int) {
+	fingerprint := hex.EncodeToString(c.hash.Sum(nil))
+	for _, r := range s.rules {
+		if r.fingerprint != "" && r.fingerprint == fingerprint {
+			return r.text, 0
+		}
+	}
+	for _, r := range s.rules {
+		if r.fingerprint == "" && r.chunks == len(c.sizes) {
+			return r.text, r.chunks
+		}
+	}
+	return s.defaultText, 0
+}
+
+// expected is the transcript expected after k chunks of a cycle: the first
+// TranscribeChunks rule of k chunks or more, or DefaultTranscript.
+// Fingerprints are known only at the flush.
+func (s *Server) expected(k int) (string, int) {
+	for _, r := range s.rules {
+		if r.fingerprint == "" && r.chunks >= k {
+			return r.text, r.chunks
+		}
+	}
+	return s.defaultText, 0
+}
+
+// utterances of a transcript: lines of a TranscribeChunks rule of n chunks,
+// or the whole text
+func utterances(text string, n int) []string {
+	if n == 0 {
+		return []string{text}
+	}
+	return strings.Split(text, "\n")
+}
+
+// first chunk of utterance j of m over n chunks
+func utteranceStart(j int, m int, n int) int {
+	return j * n / m
+}
+
+type voice struct {
+	resultType  string
+	bytesPerMs  int
+	nextVoiceID int64
+	cycle       *cycle   // audio since the last flush
+	pending     []Result // returned by the next response
+}
+
+// cycle is audio sent between flushes
+type cycle struct {
+	sizes     []int // of chunks
+	hash      hash.Hash
+	utterance int    // index of the current utterance
+	start     int    // chunk starting the current utterance
+	partial   string // the last partial of the current utterance
+}
+
+func newVoice(audioType string, resultType string) *voice {
+	if resultType == "" {
+		resultType = "one_best"
+	}
+	return &voice{resultType: resultType, bytesPerMs: bytesPerMs(audioType), nextVoiceID: 1}
+}
+
+// bytes of a millisecond of audio, for word timings
+func bytesPerMs(audioType string) int {
+	mediaType, params, _ := mime.ParseMediaType(audioType)
+	rate := 16000
+	if r, err := strconv.Atoi(params["rate"]); err == nil && r > 0 {
+		rate = r
+	}
+	bits := 16
+	if mediaType == "audio/x-adpcm" {
+		bits = 4
+	}
+	if n := rate * bits / 8 / 1000; n > 0 {
+		return n
+	}
+	return 1
+}
+
+func (s *Server) serveVoice(w http.ResponseWriter, r *http.Request, req *Request, drop bool) {
+	var audio []byte
+	var err error
+	switch req.Endpoint {
+	case EndpointSend:
+		req.VoiceID, req.Fields, audio, err = readVoice(r)
+	case EndpointFlush:
+		var body struct {
+			VoiceID int64 `json:"voice_id"`
+		}
+		err = json.NewDecoder(r.Body).Decode(&body)
+		req.VoiceID = body.VoiceID
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.AudioSize = len(audio)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.voices[req.Voice]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no such voice")
+		return
+	}
+	switch req.Endpoint {
+	case EndpointDelete:
+		delete(s.voices, req.Voice)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case EndpointSend, EndpointFlush:
+		if req.VoiceID != v.nextVoiceID {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("voice_id %d is out of order: want %d", req.VoiceID, v.nextVoiceID))
+			return
+		}
+	}
+
+	if req.Endpoint == EndpointSend {
+		v.nextVoiceID++
+		v.send(s, audio)
+	}
+	rs := v.pending
+	v.pending = nil
+	if req.Endpoint == EndpointFlush {
+		// results come to following requests
+		v.flush(s)
+	}
+	if drop {
+		var kept []Result
+		for _, r := range rs {
+			if r.Type == "NO_DATA" {
+				kept = append(kept, r)
+			}
+		}
+		rs = kept
+	}
+	writeJSON(w, encodeResults(rs, v.resultType))
+}
+
+// readVoice reads the multipart form of send
+func readVoice(r *http.Request) (voiceID int64, fields []string, audio []byte, err error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, nil, nil, err
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		fields = append(fields, part.FormName())
+		switch part.FormName() {
+		case "voice_id":
+			voiceID, err = strconv.ParseInt(string(data), 10, 64)
+			if err != nil {
+				return 0, nil, nil, fmt.Errorf("invalid voice_id: %q", data)
+			}
+		case "voice":
+			audio = data
+		}
+	}
+	if voiceID == 0 || audio == nil {
+		return 0, nil, nil, fmt.Errorf("voice_id and voice are required")
+	}
+	return voiceID, fields, audio, nil
+}
+
+// send adds audio to the cycle. The first audio of a cycle makes SOS, and
+// chunks make partials growing to the expected utterance.
+func (v *voice) send(s *Server, audio []byte) {
+	if v.cycle == nil {
+		v.cycle = &cycle{hash: sha256.New()}
+		if s.script == nil || scriptSOS(s.script) {
+			v.pending = append(v.pending, Result{Type: "SOS"})
+		}
+	}
+	c := v.cycle
+	c.sizes = append(c.sizes, len(audio))
+	c.hash.Write(audio)
+	if s.script != nil {
+		return
+	}
+	text, n := s.expected(len(c.sizes))
+	lines := utterances(text, n)
+	i := len(c.sizes) - 1
+	for c.utterance+1 < len(lines) && utteranceStart(c.utterance+1, len(lines), n) <= i {
+		if lines[c.utterance] != "" {
+			v.pending = append(v.pending, v.result(lines[c.utterance], c.sizes[c.start:i]))
+		}
+		v.pending = append(v.pending, Result{Type: "SOS"})
+		c.utterance++
+		c.start = i
+		c.partial = ""
+	}
+	if c.utterance >= len(lines) {
+		return
+	}
+	// chunks of the utterance so far, and in all. The last one is left
+	// for the final result
+	k, total := i+1-c.start, i+2-c.start
+	if n > 0 {
+		total = n - c.start
+		if c.utterance+1 < len(lines) {
+			total = utteranceStart(c.utterance+1, len(lines), n) - c.start
+		}
+	}
+	rs := []rune(lines[c.utterance])
+	if k >= total {
+		return
+	}
+	if partial := string(rs[:len(rs)*k/total]); partial != "" && partial != c.partial {
+		v.pending = append(v.pending, Result{Type: "TMP_RESULT", Text: partial})
+		c.partial = partial
+	}
+}
+
+// result of an utterance over chunks of sizes. Words are timed from the
+// start of the utterance.
+func (v *voice) result(text string, sizes []int) Result {
+	size := 0
+	for _, n := range sizes {
+		size += n
+	}
+	duration := int64(size / v.bytesPerMs)
+	return Result{Type: "RESULT", Text: text, Confidence: 1, Words: splitWords(text, duration)}
+}
+
+func scriptSOS(script []Result) bool {
+	return len(script) > 0 && script[0].Type == "SOS"
+}
+
+// flush finishes the cycle with the final result of the last utterance
+// and NO_DATA.
+func (v *voice) flush(s *Server) {
+	c := v.cycle
+	v.cycle = nil
+	if s.script != nil {
+		script := s.script
+		if c != nil && scriptSOS(script) {
+			script = script[1:] // returned by send
+		}
+		v.pending = append(append(v.pending, script...), Result{Type: "NO_DATA"})
+		return
+	}
+	if c == nil {
+		v.pending = append(v.pending, Result{Type: "NO_DATA"})
+		return
+	}
+	lines := utterances(s.transcript(c))
+	if c.utterance < len(lines) {
+		if text := strings.Join(lines[c.utterance:], "\n"); text != "" {
+			v.pending = append(v.pending, v.result(text, c.sizes[c.start:]))
+		}
+	}
+	v.pending = append(v.pending, Result{Type: "NO_DATA"})
+}
+
+// splitWords splits text at spaces, and spreads words over duration by
+// their lengths.
+func splitWords(text string, duration int64) []Word {
+	fields := strings.Fields(text)
+	total := int64(utf8.RuneCountInString(strings.Join(fields, "")))
+	var words []Word
+	var pos int64
+	for _, f := range fields {
+		n := int64(utf8.RuneCountInString(f))
+		words = append(words, Word{
+			Str:        f,
+			Confidence: 1,
+			Begin:      duration * pos / total,
+			End:        duration * (pos + n) / total,
+		})
+		pos += n
+	}
+	return words
+}
+
+// encodeResults formats results like the API, e.g. [["RESULT", "text"]]
+// for one_best.
+func encodeResults(rs []Result, resultType string) interface{} {
+	out := []interface{}{}
+	for _, r := range rs {
+		if resultType != "nbest" {
+			out = append(out, [2]string{r.Type, r.Text})
+			continue
+		}
+		var result interface{} = r.Text
+		if r.Type == "RESULT" {
+			words := r.Words
+			if words == nil {
+				words = []Word{}
+			}
+			result = []interface{}{map[string]interface{}{
+				"str":        r.Text,
+				"confidence": r.Confidence,
+				"words":      words,
+			}}
+		}
+		out = append(out, map[string]interface{}{"type": r.Type, "status": "", "result": result})
+	}
+	return out
+}
@@ -0,0 +1,323 @@
+package recaius
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span is a detected part of text in byte offsets.
+type Span struct {
+	Start int
+	End   int
+	Kind  string
+}
+
+// Detector finds sensitive information in text.
+type Detector interface {
+	Detect(text string) []Span
+}
+
+// RegexDetector detects matches of a pattern.
+type RegexDetector struct {
+	Kind    string
+	Pattern *regexp.Regexp
+}
+
+func (d *RegexDetector) Detect(text string) []Span {
+	var spans []Span
+	for _, m := range d.Pattern.FindAllStringIndex(text, -1) {
+		spans = append(spans, Span{Start: m[0], End: m[1], Kind: d.Kind})
+	}
+	return spans
+}
+
+// NewEmailDetector detects e-mail addresses.
+func NewEmailDetector() *RegexDetector {
+	return &RegexDetector{
+		Kind:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+ ?@ ?[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	}
+}
+
+// DictionaryDetector detects words in a list, e.g. names of customers.
+type DictionaryDetector struct {
+	Kind  string
+	Words []string
+}
+
+func (d *DictionaryDetector) Detect(text string) []Span {
+	var spans []Span
+	for _, w := range d.Words {
+		if w == "" {
+			continue
+		}
+		for p := 0; ; {
+			i := strings.Index(text[p:], w)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, Span{Start: p + i, End: p + i + len(w), Kind: d.Kind})
+			p += i + len(w)
+		}
+	}
+	return spans
+}
+
+// DigitSequenceDetector detects long numbers like phone or card numbers,
+// even if digits are spoken as separate words. Digits may be arabic or kanji,
+// separated by spaces, hyphens, commas or の.
+type DigitSequenceDetector struct {
+	Kind      string
+	MinDigits int // default 7
+}
+
+func (d *DigitSequenceDetector) Detect(text string) []Span {
+	min := d.MinDigits
+	if min <= 0 {
+		min = 7
+	}
+	var spans []Span
+	start, end, digits := -1, -1, 0
+	flush := func() {
+		if digits >= min {
+			spans = append(spans, Span{Start: start, End: end, Kind: d.Kind})
+		}
+		start, end, digits = -1, -1, 0
+	}
+	for i, r := range text {
+		if isDigitRune(r) {
+			if start < 0 {
+				start = i
+			}
+			end = i + utf8.RuneLen(r)
+			digits++
+		} else if start >= 0 && !strings.ContainsRune(" 　-－ー−‐、,の", r) {
+			flush()
+		}
+	}
+	flush()
+	return spans
+}
+
+func isDigitRune(r rune) bool {
+	_, kanji := kanjiDigits[r]
+	return unicode.IsDigit(r) || kanji
+}
+
+// DefaultDetectors detects e-mail addresses and numbers of 7 or more digits.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		NewEmailDetector(),
+		&DigitSequenceDetector{Kind: "number"},
+	}
+}
+
+// RedactedSpan is a redacted part of an utterance. The original text is
+// not kept. Start and End are set if the result has word timings.
+type RedactedSpan struct {
+	Kind    string
+	Length  int // runes of the original text
+	Start   time.Duration
+	End     time.Duration
+	HasTime bool
+}
+
+// Redactor is an AsrResultProcessor which masks sensitive information in
+// texts and words. Time ranges of redacted words are reported to OnRedact,
+// or recorded with Record, so that the audio can be redacted too.
+type Redactor struct {
+	Detectors []Detector
+	Mask      rune               // default '*'
+	OnRedact  func(RedactedSpan) // called for each redacted span of final results
+	Record    bool               // keep redacted spans for Spans and TimeRanges until Drain
+
+	mu    sync.Mutex
+	spans []RedactedSpan
+}
+
+func NewRedactor(detectors ...Detector) *Redactor {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+	return &Redactor{Detectors: detectors}
+}
+
+// Spans returns recorded spans of final results.
+func (rd *Redactor) Spans() []RedactedSpan {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	return append([]RedactedSpan(nil), rd.spans...)
+}
+
+// Drain returns recorded spans, and forgets them, e.g. after each file or
+// periodically on a long stream.
+func (rd *Redactor) Drain() []RedactedSpan {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	spans := rd.spans
+	rd.spans = nil
+	return spans
+}
+
+// TimeRanges returns time ranges of recorded spans with word timings.
+func (rd *Redactor) TimeRanges() []Interval {
+	var ivs []Interval
+	for _, s := range rd.Spans() {
+		if s.HasTime {
+			ivs = append(ivs, Interval{Start: s.Start, End: s.End, Text: s.Kind})
+		}
+	}
+	return ivs
+}
+
+func (rd *Redactor) Process(r *AsrResult) {
+	text := r.OneBest.Str
+	var spans []Span
+	r.OneBest.Str, spans = rd.redact(text)
+	if r.Type == "RESULT" {
+		// one_best has no timing
+		for _, s := range spans {
+			rd.record(RedactedSpan{Kind: s.Kind, Length: utf8.RuneCountInString(text[s.Start:s.End])})
+		}
+	}
+	r.NBest.ResultTemp, _ = rd.redact(r.NBest.ResultTemp)
+	// every alternative is masked, but spans are recorded only for the best
+	// one, so that an utterance is not reported many times
+	for i := range r.NBest.Result {
+		rd.processElement(&r.NBest.Result[i], i == 0)
+	}
+}
+
+func (rd *Redactor) processElement(e *AsrNBestElement, record bool) {
+	text := e.Str
+	masked, spans := rd.redact(text)
+	e.Str = masked
+	if len(spans) == 0 {
+		return
+	}
+
+	// locate words in the text to find words in spans
+	type located struct{ start, end int }
+	locs := make([]located, len(e.Words))
+	p := 0
+	for i, w := range e.Words {
+		locs[i] = located{-1, -1}
+		if w.Str == "" {
+			continue
+		}
+		if j := strings.Index(text[p:], w.Str); j >= 0 {
+			locs[i] = located{p + j, p + j + len(w.Str)}
+			p += j + len(w.Str)
+		}
+	}
+	for _, s := range spans {
+		rs := RedactedSpan{Kind: s.Kind, Length: utf8.RuneCountInString(text[s.Start:s.End])}
+		// words between the located words around the span: ones in the
+		// span, and ones not located in the text, e.g. normalized
+		// differently, which may be in the span
+		lo, hi := -1, len(e.Words)
+		for i, l := range locs {
+			if l.start < 0 {
+				continue
+			}
+			if l.end <= s.Start {
+				lo = i
+			} else if l.start >= s.End && hi == len(e.Words) {
+				hi = i
+			}
+		}
+		from, to := lo+1, hi
+		if from == to {
+			// no word for the span: fail closed with the words around it
+			if lo >= 0 {
+				from = lo
+			}
+			if hi < len(e.Words) {
+				to = hi + 1
+			}
+		}
+		for i := from; i < to; i++ {
+			w := &e.Words[i]
+			if !rs.HasTime || w.BeginTime() < rs.Start {
+				rs.Start = w.BeginTime()
+			}
+			if !rs.HasTime || w.EndTime() > rs.End {
+				rs.End = w.EndTime()
+			}
+			rs.HasTime = true
+			w.Str = rd.mask(w.Str)
+			w.Yomi = rd.mask(w.Yomi)
+		}
+		if record {
+			rd.record(rs)
+		}
+	}
+}
+
+func (rd *Redactor) record(s RedactedSpan) {
+	if rd.Record {
+		rd.mu.Lock()
+		rd.spans = append(rd.spans, s)
+		rd.mu.Unlock()
+	}
+	if rd.OnRedact != nil {
+		rd.OnRedact(s)
+	}
+}
+
+// merged spans detected by all detectors
+func (rd *Redactor) detect(text string) []Span {
+	var spans []Span
+	for _, d := range rd.Detectors {
+		spans = append(spans, d.Detect(text)...)
+	}
+	return mergeSpans(spans)
+}
+
+func (rd *Redactor) redact(text string) (string, []Span) {
+	if text == "" {
+		return text, nil
+	}
+	spans := rd.detect(text)
+	if len(spans) == 0 {
+		return text, nil
+	}
+	var b strings.Builder
+	p := 0
+	for _, s := range spans {
+		b.WriteString(text[p:s.Start])
+		b.WriteString(rd.mask(text[s.Start:s.End]))
+		p = s.End
+	}
+	b.WriteString(text[p:])
+	return b.String(), spans
+}
+
+func (rd *Redactor) mask(s string) string {
+	m := rd.Mask
+	if m == 0 {
+		m = '*'
+	}
+	return strings.Repeat(string(m), utf8.RuneCountInString(s))
+}
+
+// sort and merge overlapping spans
+func mergeSpans(spans []Span) []Span {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	var merged []Span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.Start < merged[n-1].End {
+			if s.End > merged[n-1].End {
+				merged[n-1].End = s.End
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
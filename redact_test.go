@@ -0,0 +1,129 @@
+package recaius
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRedactor(t *testing.T) {
+	rd := NewRedactor(append(DefaultDetectors(), &DictionaryDetector{Kind: "name", Words: []string{"田中"}})...)
+	rd.Record = true
+	r := AsrResult{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{{
+		Str: "田中です番号は090 1234 5678です",
+		Words: []AsrNBestWord{
+			{Str: "田中", Yomi: "タナカ", Begin: 0, End: 400},
+			{Str: "です", Begin: 400, End: 600},
+			{Str: "番号", Begin: 700, End: 1000},
+			{Str: "は", Begin: 1000, End: 1100},
+			{Str: "090", Begin: 1200, End: 1600},
+			{Str: "1234", Begin: 1700, End: 2200},
+			{Str: "5678", Begin: 2300, End: 2800},
+			{Str: "です", Begin: 2800, End: 3000},
+		},
+	}}}}
+	rd.Process(&r)
+
+	e := r.NBest.Result[0]
+	if e.Str != "**です番号は*************です" {
+		t.Fatal("unexpected text:", e.Str)
+	}
+	if e.Words[0].Str != "**" || e.Words[0].Yomi != "***" || e.Words[5].Str != "****" || e.Words[7].Str != "です" {
+		t.Fatal("unexpected words:", e.Words)
+	}
+	ivs := rd.TimeRanges()
+	if len(ivs) != 2 {
+		t.Fatal("unexpected time ranges:", ivs)
+	}
+	if ivs[1].Start != 1200*time.Millisecond || ivs[1].End != 2800*time.Millisecond || ivs[1].Text != "number" {
+		t.Fatal("unexpected time range of number:", ivs[1])
+	}
+	if spans := rd.Drain(); len(spans) != 2 || spans[1].Length != len("090 1234 5678") {
+		t.Fatal("unexpected spans:", spans)
+	}
+	if spans := rd.Spans(); len(spans) != 0 {
+		t.Fatal("spans are not drained:", spans)
+	}
+}
+
+func TestRedactorRecord(t *testing.T) {
+	rd := NewRedactor()
+	var reported []RedactedSpan
+	rd.OnRedact = func(s RedactedSpan) { reported = append(reported, s) }
+	r := AsrResult{Type: "RESULT", OneBest: AsrOneBest{Type: "RESULT", Str: "090-1234-5678です"}}
+	rd.Process(&r)
+	if r.OneBest.Str != "*************です" {
+		t.Fatal("unexpected text:", r.OneBest.Str)
+	}
+	if len(reported) != 1 || reported[0].Kind != "number" || reported[0].Length != 13 {
+		t.Fatal("unexpected reported spans:", reported)
+	}
+	if spans := rd.Spans(); len(spans) != 0 {
+		t.Fatal("spans are recorded without Record:", spans)
+	}
+}
+
+func TestDigitSequenceDetector(t *testing.T) {
+	d := &DigitSequenceDetector{MinDigits: 7}
+	if spans := d.Detect("ゼロは〇九〇の一二三四の五六七八です"); len(spans) != 1 {
+		t.Fatal("kanji digits not detected:", spans)
+	}
+	if spans := d.Detect("3人で10分"); len(spans) != 0 {
+		t.Fatal("short numbers detected:", spans)
+	}
+}
+
+func TestRedactorAlternatives(t *testing.T) {
+	rd := NewRedactor(&DictionaryDetector{Kind: "name", Words: []string{"田中", "中田"}})
+	rd.Record = true
+	redacted := 0
+	rd.OnRedact = func(RedactedSpan) { redacted++ }
+	r := AsrResult{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{
+		{Str: "田中です", Words: []AsrNBestWord{{Str: "田中", Begin: 0, End: 400}, {Str: "です", Begin: 400, End: 600}}},
+		{Str: "中田です", Words: []AsrNBestWord{{Str: "中田", Begin: 0, End: 400}, {Str: "です", Begin: 400, End: 600}}},
+		{Str: "田中デス", Words: []AsrNBestWord{{Str: "田中", Begin: 0, End: 400}, {Str: "デス", Begin: 400, End: 600}}},
+	}}}
+	rd.Process(&r)
+
+	for _, e := range r.NBest.Result {
+		if e.Str[:len("**")] != "**" || e.Words[0].Str != "**" {
+			t.Fatal("alternative is not masked:", e)
+		}
+	}
+	if spans := rd.Spans(); len(spans) != 1 || spans[0].Length != 2 || redacted != 1 {
+		t.Fatal("spans are recorded for alternatives:", spans, redacted)
+	}
+	if ivs := rd.TimeRanges(); len(ivs) != 1 || ivs[0].End != 400*time.Millisecond {
+		t.Fatal("unexpected time ranges:", ivs)
+	}
+}
+
+func TestRedactorUnlocatedWords(t *testing.T) {
+	rd := NewRedactor(&DictionaryDetector{Kind: "name", Words: []string{"田中"}}, &DigitSequenceDetector{Kind: "number"})
+	rd.Record = true
+	result := func(text string, words ...AsrNBestWord) AsrResult {
+		return AsrResult{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{{Str: text, Words: words}}}}
+	}
+
+	// words spelled differently from the text
+	r := result("田中です", AsrNBestWord{Str: "たなか", Begin: 0, End: 400}, AsrNBestWord{Str: "です", Begin: 400, End: 600})
+	rd.Process(&r)
+	if words := r.NBest.Result[0].Words; words[0].Str != "***" || words[1].Str != "です" {
+		t.Fatal("unlocated word is not masked:", words)
+	}
+	if ivs := rd.TimeRanges(); len(ivs) != 1 || ivs[0].End != 400*time.Millisecond {
+		t.Fatal("unexpected time ranges:", ivs)
+	}
+
+	// no words for the number
+	r = result("番号は0901234567です",
+		AsrNBestWord{Str: "番号", Begin: 0, End: 300},
+		AsrNBestWord{Str: "は", Begin: 300, End: 400},
+		AsrNBestWord{Str: "です", Begin: 1500, End: 1700})
+	rd.Process(&r)
+	if words := r.NBest.Result[0].Words; words[0].Str != "番号" || words[1].Str != "*" || words[2].Str != "**" {
+		t.Fatal("words around the number are not masked:", words)
+	}
+	if ivs := rd.TimeRanges(); len(ivs) != 2 || ivs[1].Start != 300*time.Millisecond || ivs[1].End != 1700*time.Millisecond {
+		t.Fatal("unexpected time ranges:", ivs)
+	}
+}
@@ -0,0 +1,158 @@
+package recaius
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SubtitleOptions controls line breaking of cues.
+type SubtitleOptions struct {
+	MaxLineChars int           // default 20
+	MaxLines     int           // default 2
+	MaxDuration  time.Duration // of a cue. default 6s
+	MinGap       time.Duration // between cues. default 0
+}
+
+func (o SubtitleOptions) maxLineChars() int {
+	if o.MaxLineChars <= 0 {
+		return 20
+	}
+	return o.MaxLineChars
+}
+
+func (o SubtitleOptions) maxLines() int {
+	if o.MaxLines <= 0 {
+		return 2
+	}
+	return o.MaxLines
+}
+
+func (o SubtitleOptions) maxDuration() time.Duration {
+	if o.MaxDuration <= 0 {
+		return 6 * time.Second
+	}
+	return o.MaxDuration
+}
+
+// Cue is a caption shown between Start and End.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Lines []string
+}
+
+// BuildCues makes cues from word timings of utterances. A cue never spans
+// utterances, and lines are broken only between words. Utterances without
+// words (e.g. one_best results) are skipped.
+func BuildCues(us []Utterance, opt SubtitleOptions) []Cue {
+	var cues []Cue
+	for _, u := range us {
+		var cue *Cue
+		for _, w := range u.Words {
+			begin := w.BeginTime()
+			end := w.EndTime()
+			if cue != nil && !isClosingPunct(w.Str) && end-cue.Start > opt.maxDuration() {
+				cues = append(cues, *cue)
+				cue = nil
+			}
+			if cue == nil {
+				cue = &Cue{Start: begin, Lines: []string{""}}
+			}
+			last := &cue.Lines[len(cue.Lines)-1]
+			text := joinWord(*last, w.Str)
+			switch {
+			case *last == "" || isClosingPunct(w.Str) || utf8.RuneCountInString(text) <= opt.maxLineChars():
+				*last = text
+			case len(cue.Lines) < opt.maxLines():
+				cue.Lines = append(cue.Lines, w.Str)
+			default:
+				cues = append(cues, *cue)
+				cue = &Cue{Start: begin, Lines: []string{w.Str}}
+			}
+			cue.End = end
+		}
+		if cue != nil {
+			cues = append(cues, *cue)
+		}
+	}
+	for i := 0; i+1 < len(cues); i++ {
+		if limit := cues[i+1].Start - opt.MinGap; cues[i].End > limit {
+			cues[i].End = limit
+			if cues[i].End < cues[i].Start {
+				cues[i].End = cues[i].Start
+			}
+		}
+	}
+	return cues
+}
+
+// BuildTranscriptCues is BuildCues for utterances of a transcript.
+func BuildTranscriptCues(t *Transcript, opt SubtitleOptions) []Cue {
+	return BuildCues(t.Utterances(), opt)
+}
+
+// join a word to a line, with a space only between alphanumeric words
+func joinWord(line string, word string) string {
+	if line == "" {
+		return word
+	}
+	l, _ := utf8.DecodeLastRuneInString(line)
+	r, _ := utf8.DecodeRuneInString(word)
+	if isLatin(l) && isLatin(r) {
+		return line + " " + word
+	}
+	return line + word
+}
+
+func isLatin(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// punctuation which must not start a line
+func isClosingPunct(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("、。，．,.!?！？」』）)】ー", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n", i+1, formatCueTime(c.Start, ","), formatCueTime(c.End, ","))
+		for _, l := range c.Lines {
+			fmt.Fprintln(bw, l)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+func WriteVTT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "WEBVTT\n\n")
+	for _, c := range cues {
+		fmt.Fprintf(bw, "%s --> %s\n", formatCueTime(c.Start, "."), formatCueTime(c.End, "."))
+		for _, l := range c.Lines {
+			fmt.Fprintln(bw, l)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+// hh:mm:ss,mmm (SRT) or hh:mm:ss.mmm (WebVTT)
+func formatCueTime(d time.Duration, sep string) string {
+	ms := int64(d / time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,81 @@
+package recaius
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func subtitleUtterances() []Utterance {
+	return []Utterance{
+		{Text: "本日は晴天なり。", Words: []AsrNBestWord{
+			{Str: "本日", Begin: 500, End: 900},
+			{Str: "は", Begin: 900, End: 1000},
+			{Str: "晴天", Begin: 1000, End: 1500},
+			{Str: "なり", Begin: 1500, End: 1800},
+			{Str: "。", Begin: 1800, End: 1850},
+		}},
+		{Text: "音声認識の結果を字幕として表示するためのテストを行っています", Words: []AsrNBestWord{
+			{Str: "音声", Begin: 1900, End: 2300},
+			{Str: "認識", Begin: 2300, End: 2700},
+			{Str: "の", Begin: 2700, End: 2800},
+			{Str: "結果", Begin: 2800, End: 3200},
+			{Str: "を", Begin: 3200, End: 3300},
+			{Str: "字幕", Begin: 3300, End: 3700},
+			{Str: "として", Begin: 3700, End: 4000},
+			{Str: "表示", Begin: 4000, End: 4400},
+			{Str: "する", Begin: 4400, End: 4600},
+			{Str: "ため", Begin: 4600, End: 4900},
+			{Str: "の", Begin: 4900, End: 5000},
+			{Str: "テスト", Begin: 5000, End: 5500},
+			{Str: "を", Begin: 5500, End: 5600},
+			{Str: "行って", Begin: 5600, End: 6000},
+			{Str: "います", Begin: 6000, End: 6500},
+		}},
+		{Text: "hello world", Words: []AsrNBestWord{
+			{Str: "hello", Begin: 7000, End: 7400},
+			{Str: "world", Begin: 7400, End: 7900},
+		}},
+	}
+}
+
+func TestSubtitle(t *testing.T) {
+	opt := SubtitleOptions{MaxLineChars: 8, MaxLines: 2, MaxDuration: 4 * time.Second, MinGap: 100 * time.Millisecond}
+	cues := BuildCues(subtitleUtterances(), opt)
+	for _, c := range cues {
+		if c.End-c.Start > opt.MaxDuration {
+			t.Fatal("cue too long:", c)
+		}
+	}
+
+	var srt, vtt bytes.Buffer
+	if err := WriteSRT(&srt, cues); err != nil {
+		t.Fatal("srt error:", err)
+	}
+	if err := WriteVTT(&vtt, cues); err != nil {
+		t.Fatal("vtt error:", err)
+	}
+	checkGolden(t, "subtitle.srt", srt.Bytes())
+	checkGolden(t, "subtitle.vtt", vtt.Bytes())
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal("update golden error:", err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("golden file error:", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch:\n--- got\n%s\n--- want\n%s", name, got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package recaius
+
+import (
+	"strings"
+	"sync"
+)
+
+// Utterance is a finally recognized sentence.
+type Utterance struct {
+	Text       string
+	Confidence float64           // of the best hypothesis. 0 for one_best
+	Words      []AsrNBestWord    // of the best hypothesis. nil for one_best
+	NBest      []AsrNBestElement // alternatives including the best
+}
+
+// Transcript assembles recognition results into utterances.
+// TMP_RESULT updates the current partial text, and RESULT commits it as an
+// utterance. It is safe to Add from another goroutine while reading.
+type Transcript struct {
+	mu         sync.Mutex
+	utterances []Utterance
+	partial    string
+}
+
+func NewTranscript(rs ...AsrResult) *Transcript {
+	t := &Transcript{}
+	t.Add(rs...)
+	return t
+}
+
+// Add updates the transcript with results in arrival order.
+// Results with an error are ignored.
+func (t *Transcript) Add(rs ...AsrResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range rs {
+		if r.Err != nil {
+			continue
+		}
+		switch r.Type {
+		case "SOS":
+			t.partial = ""
+		case "TMP_RESULT":
+			t.partial = r.OneBest.Str
+			if r.NBest.Type != "" {
+				t.partial = r.NBest.ResultTemp
+			}
+		case "RESULT":
+			t.partial = ""
+			if u, ok := newUtterance(r); ok {
+				t.utterances = append(t.utterances, u)
+			}
+		}
+	}
+}
+
+// Watch adds results from a channel (e.g. AsrStreamSession.Response) until
+// it is closed.
+func (t *Transcript) Watch(ch <-chan AsrResult) {
+	for r := range ch {
+		t.Add(r)
+	}
+}
+
+func newUtterance(r AsrResult) (Utterance, bool) {
+	if r.NBest.Type == "" {
+		if r.OneBest.Str == "" {
+			return Utterance{}, false
+		}
+		return Utterance{Text: r.OneBest.Str}, true
+	}
+	if len(r.NBest.Result) == 0 {
+		return Utterance{}, false
+	}
+	best := r.NBest.Result[0]
+	return Utterance{
+		Text:       best.Str,
+		Confidence: best.Confidence,
+		Words:      best.Words,
+		NBest:      r.NBest.Result,
+	}, true
+}
+
+// Text returns final texts, one utterance per line.
+func (t *Transcript) Text() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	texts := make([]string, len(t.utterances))
+	for i, u := range t.utterances {
+		texts[i] = u.Text
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Partial returns the text of the utterance under recognition.
+func (t *Transcript) Partial() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.partial
+}
+
+func (t *Transcript) Utterances() []Utterance {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Utterance(nil), t.utterances...)
+}
+
+// Words returns words of the best hypotheses of all utterances.
+func (t *Transcript) Words() []AsrNBestWord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var words []AsrNBestWord
+	for _, u := range t.utterances {
+		words = append(words, u.Words...)
+	}
+	return words
+}
@@ -0,0 +1,34 @@
+package recaius
+
+import "testing"
+
+func TestTranscript(t *testing.T) {
+	tr := NewTranscript(
+		AsrResult{Type: "SOS", NBest: AsrNBest{Type: "SOS"}},
+		AsrResult{Type: "TMP_RESULT", NBest: AsrNBest{Type: "TMP_RESULT", ResultTemp: "こんに"}},
+	)
+	if tr.Partial() != "こんに" {
+		t.Fatal("unexpected partial:", tr.Partial())
+	}
+	tr.Add(AsrResult{Type: "RESULT", NBest: AsrNBest{Type: "RESULT", Result: []AsrNBestElement{
+		{Str: "こんにちは", Confidence: 0.9, Words: []AsrNBestWord{
+			{Str: "こんにちは", Yomi: "コンニチワ", Begin: 100, End: 800},
+		}},
+		{Str: "こんにちわ", Confidence: 0.5},
+	}}})
+	tr.Add(AsrResult{Type: "RESULT", OneBest: AsrOneBest{Type: "RESULT", Str: "さようなら"}})
+
+	if tr.Partial() != "" {
+		t.Fatal("partial remains:", tr.Partial())
+	}
+	if tr.Text() != "こんにちは\nさようなら" {
+		t.Fatal("unexpected text:", tr.Text())
+	}
+	us := tr.Utterances()
+	if len(us) != 2 || us[0].Confidence != 0.9 || len(us[0].NBest) != 2 {
+		t.Fatal("unexpected utterances:", us)
+	}
+	if ws := tr.Words(); len(ws) != 1 || ws[0].End != 800 {
+		t.Fatal("unexpected words:", ws)
+	}
+}
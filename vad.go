@@ -0,0 +1,262 @@
+package recaius
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// VAD is a simple voice activity detector based on frame energy and
+// zero-crossing rate. It works on 16bit little endian linear PCM (mono).
+type VAD struct {
+	SampleRate      int           // Hz. default 16000
+	FrameDuration   time.Duration // default 20ms
+	EnergyThreshold float64       // RMS of 16bit samples. default 300
+	ZCRThreshold    float64       // zero crossings per sample. default 0.25
+	Hangover        time.Duration // speech continues this long after the energy drops. default 300ms
+}
+
+func (v *VAD) sampleRate() int {
+	if v.SampleRate <= 0 {
+		return 16000
+	}
+	return v.SampleRate
+}
+
+func (v *VAD) frameSize() int {
+	d := v.FrameDuration
+	if d <= 0 {
+		d = 20 * time.Millisecond
+	}
+	n := int(int64(v.sampleRate())*int64(d)/int64(time.Second)) * 2
+	if n < 2 {
+		return 2 // at least one sample
+	}
+	return n
+}
+
+func (v *VAD) energyThreshold() float64 {
+	if v.EnergyThreshold <= 0 {
+		return 300
+	}
+	return v.EnergyThreshold
+}
+
+func (v *VAD) zcrThreshold() float64 {
+	if v.ZCRThreshold <= 0 {
+		return 0.25
+	}
+	return v.ZCRThreshold
+}
+
+func (v *VAD) hangover() time.Duration {
+	if v.Hangover <= 0 {
+		return 300 * time.Millisecond
+	}
+	return v.Hangover
+}
+
+// IsSpeech reports whether a frame looks like speech, without hangover.
+// Quiet frames with high zero-crossing rate (e.g. fricatives) are also speech.
+func (v *VAD) IsSpeech(frame []byte) bool {
+	rms, zcr := frameFeatures(frame)
+	th := v.energyThreshold()
+	return rms >= th || rms >= th/4 && zcr >= v.zcrThreshold()
+}
+
+func frameFeatures(frame []byte) (rms float64, zcr float64) {
+	n := len(frame) / 2
+	if n == 0 {
+		return 0, 0
+	}
+	var sum float64
+	var crossings int
+	var prev int16
+	for i := 0; i < n; i++ {
+		x := int16(binary.LittleEndian.Uint16(frame[i*2:]))
+		sum += float64(x) * float64(x)
+		if i > 0 && (x >= 0) != (prev >= 0) {
+			crossings++
+		}
+		prev = x
+	}
+	rms = math.Sqrt(sum / float64(n))
+	if n > 1 {
+		zcr = float64(crossings) / float64(n-1)
+	}
+	return rms, zcr
+}
+
+// TimeSegment is a span of audio which was sent to the server.
+type TimeSegment struct {
+	Sent     time.Duration // position in the sent audio
+	Original time.Duration // position in the original audio
+	Length   time.Duration
+}
+
+// TimeMap maps positions in the sent audio back to the original audio,
+// after silences are dropped.
+type TimeMap struct {
+	Segments []TimeSegment
+}
+
+func (m *TimeMap) add(sent, original, length time.Duration) {
+	if n := len(m.Segments); n > 0 {
+		last := &m.Segments[n-1]
+		if last.Sent+last.Length == sent && last.Original+last.Length == original {
+			last.Length += length
+			return
+		}
+	}
+	m.Segments = append(m.Segments, TimeSegment{Sent: sent, Original: original, Length: length})
+}
+
+// Original converts a position in the sent audio to the original audio.
+func (m *TimeMap) Original(sent time.Duration) time.Duration {
+	i := sort.Search(len(m.Segments), func(i int) bool {
+		return m.Segments[i].Sent > sent
+	})
+	if i == 0 {
+		return sent
+	}
+	s := m.Segments[i-1]
+	return s.Original + sent - s.Sent
+}
+
+// VADWriter drops long silences before writing audio to the underlying
+// writer (e.g. AsrStreamSession). Up to MaxSilence of each silence is kept,
+// half at its head and half just before the next speech.
+type VADWriter struct {
+	VAD
+	MaxSilence     time.Duration // default 500ms
+	OnUtteranceEnd func() error  // called when speech turns to silence, e.g. to Flush an AsrStreamSession with KeepOpen
+
+	w        io.Writer
+	buf      []byte
+	speech   bool
+	lastTalk time.Duration // end of the last speech frame in the original audio
+	silence  time.Duration // length of the current silence written so far
+	preroll  [][]byte      // silence kept just before the next speech
+	original time.Duration
+	sent     time.Duration
+	timeMap  TimeMap
+}
+
+func NewVADWriter(w io.Writer, vad VAD) *VADWriter {
+	return &VADWriter{VAD: vad, w: w}
+}
+
+// TimeMap returns the mapping of the sent audio to the original one.
+func (vw *VADWriter) TimeMap() *TimeMap {
+	return &vw.timeMap
+}
+
+// Dropped returns the total length of the dropped audio.
+func (vw *VADWriter) Dropped() time.Duration {
+	return vw.original - vw.sent - vw.prerollDuration()
+}
+
+func (vw *VADWriter) Write(p []byte) (int, error) {
+	size := vw.frameSize()
+	vw.buf = append(vw.buf, p...)
+	for len(vw.buf) >= size {
+		if err := vw.frame(vw.buf[:size]); err != nil {
+			return len(p), err
+		}
+		vw.buf = vw.buf[size:]
+	}
+	return len(p), nil
+}
+
+// Flush processes the buffered partial frame.
+func (vw *VADWriter) Flush() error {
+	if len(vw.buf) == 0 {
+		return nil
+	}
+	buf := vw.buf
+	vw.buf = nil
+	return vw.frame(buf[:len(buf)/2*2])
+}
+
+func (vw *VADWriter) maxSilence() time.Duration {
+	if vw.MaxSilence <= 0 {
+		return 500 * time.Millisecond
+	}
+	return vw.MaxSilence
+}
+
+func (vw *VADWriter) frameDuration(frame []byte) time.Duration {
+	return time.Duration(int64(len(frame)/2) * int64(time.Second) / int64(vw.sampleRate()))
+}
+
+func (vw *VADWriter) prerollDuration() time.Duration {
+	var d time.Duration
+	for _, f := range vw.preroll {
+		d += vw.frameDuration(f)
+	}
+	return d
+}
+
+func (vw *VADWriter) frame(frame []byte) error {
+	d := vw.frameDuration(frame)
+	start := vw.original
+	vw.original += d
+
+	if vw.IsSpeech(frame) {
+		vw.lastTalk = vw.original
+		if !vw.speech {
+			vw.speech = true
+			if err := vw.writePreroll(start); err != nil {
+				return err
+			}
+		}
+		return vw.write(frame, start, d)
+	}
+	if vw.speech {
+		if vw.original-vw.lastTalk <= vw.hangover() {
+			return vw.write(frame, start, d)
+		}
+		vw.speech = false
+		vw.silence = 0
+		if vw.OnUtteranceEnd != nil {
+			if err := vw.OnUtteranceEnd(); err != nil {
+				return err
+			}
+		}
+	}
+	half := vw.maxSilence() / 2
+	if vw.silence+d <= half {
+		vw.silence += d
+		return vw.write(frame, start, d)
+	}
+	vw.preroll = append(vw.preroll, append([]byte(nil), frame...))
+	for vw.prerollDuration() > half {
+		vw.preroll = vw.preroll[1:]
+	}
+	return nil
+}
+
+// write the kept silence which ends at the given position
+func (vw *VADWriter) writePreroll(end time.Duration) error {
+	start := end - vw.prerollDuration()
+	for _, f := range vw.preroll {
+		d := vw.frameDuration(f)
+		if err := vw.write(f, start, d); err != nil {
+			return err
+		}
+		start += d
+	}
+	vw.preroll = nil
+	return nil
+}
+
+func (vw *VADWriter) write(frame []byte, original time.Duration, d time.Duration) error {
+	if _, err := vw.w.Write(frame); err != nil {
+		return err
+	}
+	vw.timeMap.add(vw.sent, original, d)
+	vw.sent += d
+	return nil
+}
@@ -0,0 +1,65 @@
+package recaius
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func makeTone(d time.Duration, amplitude float64) []byte {
+	n := int(16000 * d / time.Second)
+	buf := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		x := amplitude * math.Sin(2*math.Pi*440*float64(i)/16000)
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(int16(x)))
+	}
+	return buf
+}
+
+func TestVADWriter(t *testing.T) {
+	var out bytes.Buffer
+	utterances := 0
+	vw := NewVADWriter(&out, VAD{})
+	vw.MaxSilence = 400 * time.Millisecond
+	vw.OnUtteranceEnd = func() error {
+		utterances++
+		return nil
+	}
+	vw.Write(makeTone(time.Second, 3000))
+	vw.Write(makeTone(3*time.Second, 0))
+	vw.Write(makeTone(time.Second, 3000))
+	if err := vw.Flush(); err != nil {
+		t.Fatal("flush error:", err)
+	}
+
+	if utterances != 1 {
+		t.Fatal("unexpected utterance count:", utterances)
+	}
+	// speech 1s + hangover 0.3s + silence 0.2s + preroll 0.2s + speech 1s
+	if sent := time.Duration(out.Len()/2) * time.Second / 16000; sent != 2700*time.Millisecond {
+		t.Fatal("unexpected sent duration:", sent)
+	}
+	if d := vw.Dropped(); d != 2300*time.Millisecond {
+		t.Fatal("unexpected dropped duration:", d)
+	}
+	if o := vw.TimeMap().Original(1710 * time.Millisecond); o != 4010*time.Millisecond {
+		t.Fatal("unexpected original time:", o)
+	}
+	if o := vw.TimeMap().Original(500 * time.Millisecond); o != 500*time.Millisecond {
+		t.Fatal("unexpected original time:", o)
+	}
+}
+
+func TestVADTinyFrame(t *testing.T) {
+	v := VAD{SampleRate: 8000, FrameDuration: time.Microsecond}
+	if size := v.frameSize(); size != 2 {
+		t.Fatal("unexpected frame size:", size)
+	}
+	var out bytes.Buffer
+	vw := NewVADWriter(&out, v)
+	if n, err := vw.Write(makeTone(10*time.Millisecond, 3000)); n != 320 || err != nil {
+		t.Fatal("unexpected write:", n, err)
+	}
+}
@@ -0,0 +1,130 @@
+package recaius
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// WAVE format tags
+const (
+	wavFormatPCM = 0x0001
+)
+
+// Wav is a parsed RIFF WAVE file.
+type Wav struct {
+	Format AudioFormat
+	Tag    uint16 // format tag in the fmt chunk
+	Data   []byte
+}
+
+func ReadWavFile(path string) (*Wav, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWav(data)
+}
+
+func ReadWav(r io.Reader) (*Wav, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWav(data)
+}
+
+// ParseWav parses a RIFF WAVE file. Data refers to the given buffer.
+func ParseWav(data []byte) (*Wav, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a wav file")
+	}
+	var w Wav
+	var hasFmt bool
+	for p := 12; p+8 <= len(data); {
+		id := string(data[p : p+4])
+		size := int(binary.LittleEndian.Uint32(data[p+4 : p+8]))
+		body := data[p+8:]
+		if size > len(body) {
+			// some writers leave the size of a streamed data chunk unset
+			size = len(body)
+		}
+		body = body[:size]
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("invalid fmt chunk")
+			}
+			w.Tag = binary.LittleEndian.Uint16(body[0:2])
+			w.Format = AudioFormat{
+				Channels:      int(binary.LittleEndian.Uint16(body[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(body[4:8])),
+				BlockAlign:    int(binary.LittleEndian.Uint16(body[12:14])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(body[14:16])),
+			}
+			hasFmt = true
+		case "data":
+			w.Data = body
+		}
+		p += 8 + size + size%2
+	}
+	if !hasFmt {
+		return nil, fmt.Errorf("fmt chunk not found")
+	}
+	if w.Data == nil {
+		return nil, fmt.Errorf("data chunk not found")
+	}
+	return &w, nil
+}
+
+// WriteTo writes a RIFF WAVE file with fmt and data chunks.
+func (w *Wav) WriteTo(out io.Writer) (int64, error) {
+	tag := w.Tag
+	if tag == 0 {
+		tag = wavFormatPCM
+	}
+	size := len(w.Data)
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+size+size%2))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], tag)
+	binary.LittleEndian.PutUint16(header[22:], uint16(w.Format.Channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(w.Format.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(w.Format.BytesPerSecond()))
+	binary.LittleEndian.PutUint16(header[32:], uint16(w.Format.BlockAlign))
+	binary.LittleEndian.PutUint16(header[34:], uint16(w.Format.BitsPerSample))
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(size))
+
+	n, err := out.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+	n, err = out.Write(w.Data)
+	written += int64(n)
+	if err != nil {
+		return written, err
+	}
+	if size%2 == 1 {
+		n, err = out.Write([]byte{0})
+		written += int64(n)
+	}
+	return written, err
+}
+
+func WriteWavFile(path string, w *Wav) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := w.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}